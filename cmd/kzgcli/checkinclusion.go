@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jsign/go-kzg-ceremony-client/transcript"
+	"github.com/spf13/cobra"
+)
+
+var checkInclusionCmd = &cobra.Command{
+	Use:   "check-inclusion",
+	Short: "Checks that a contribution is included in the current sequencer transcript (or a transcript file)",
+	Run: func(cmd *cobra.Command, args []string) {
+		contributionPath, err := cmd.Flags().GetString("contribution")
+		if err != nil {
+			log.Fatalf("get --contribution flag value: %s", err)
+		}
+		if contributionPath == "" {
+			log.Fatalf("the --contribution flag is required")
+		}
+		transcriptPath, err := cmd.Flags().GetString("transcript")
+		if err != nil {
+			log.Fatalf("get --transcript flag value: %s", err)
+		}
+		identity, err := cmd.Flags().GetString("identity")
+		if err != nil {
+			log.Fatalf("get --identity flag value: %s", err)
+		}
+
+		fmt.Printf("Reading contribution file... ")
+		bc, _, err := readBatchContributionFile(contributionPath)
+		if err != nil {
+			log.Fatalf("reading contribution file: %s", err)
+		}
+		fmt.Printf("OK\n")
+
+		batchTranscript, source, err := loadTranscript(cmd, transcriptPath)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+
+		fmt.Printf("Looking for our contribution in the transcript... ")
+		inclusion, err := batchTranscript.CheckInclusion(bc, identity)
+		if errors.Is(err, transcript.ErrNotIncluded) {
+			log.Fatalf("our contribution isn't in the transcript (if it was just sent, the sequencer may not have included it yet): %s", err)
+		}
+		if err != nil {
+			log.Fatalf("our contribution isn't correctly included in the transcript: %s", err)
+		}
+		fmt.Printf("OK\n")
+		commitment, err := batchTranscript.Commitment()
+		if err != nil {
+			log.Fatalf("calculating transcript commitment: %s", err)
+		}
+
+		fmt.Printf("\nInclusion statement\n")
+		fmt.Printf("-------------------\n")
+		fmt.Printf("Contribution file:     %s\n", contributionPath)
+		fmt.Printf("Transcript:            %s\n", source)
+		fmt.Printf("Transcript commitment: 0x%x\n", commitment)
+		fmt.Printf("Participant:           %s\n", inclusion.ParticipantID)
+		fmt.Printf("Index:                 %d (of %d contributions)\n", inclusion.Index, len(batchTranscript.ParticipantIDs)-1)
+		for i, c := range bc.Contributions {
+			potPubKey := c.PotPubKey.Bytes()
+			prevRunningProduct := inclusion.PreviousRunningProducts[i].Bytes()
+			runningProduct := inclusion.RunningProducts[i].Bytes()
+			fmt.Printf("Sub-ceremony %d (%d G1 powers, %d G2 powers):\n", i, c.NumG1Powers, c.NumG2Powers)
+			fmt.Printf("  potPubKey:                0x%x\n", potPubKey)
+			fmt.Printf("  previous running product: 0x%x\n", prevRunningProduct)
+			fmt.Printf("  running product:          0x%x\n", runningProduct)
+		}
+		fmt.Printf("\nThe contribution in %s is included at index %d of the transcript with commitment 0x%x, for participant %s. ", contributionPath, inclusion.Index, commitment, inclusion.ParticipantID)
+		fmt.Printf("In every sub-ceremony our potPubKey is in the witness, and the running product is our tau first power, which pairing checks as the update of the previous running product with our secret.\n")
+		fmt.Printf("Checked by kzgcli at %s.\n", time.Now().UTC().Format(time.RFC3339))
+	},
+}
+
+// loadTranscript decodes the transcript file at path or, if path is empty, pulls the current transcript from
+// the sequencer. It also returns a description of where the transcript was loaded from.
+func loadTranscript(cmd *cobra.Command, path string) (*transcript.BatchTranscript, string, error) {
+	if path != "" {
+		fmt.Printf("Decoding transcript file... ")
+		bt, _, err := readTranscriptFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading transcript file: %s", err)
+		}
+		fmt.Printf("OK\n")
+		return bt, path, nil
+	}
+
+	sequencerURL, err := cmd.Flags().GetString("sequencer-url")
+	if err != nil {
+		return nil, "", fmt.Errorf("get --sequencer-url flag value: %s", err)
+	}
+	client, err := newSequencerClient(cmd)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating sequencer client: %s", err)
+	}
+	fmt.Printf("Pulling and decoding current transcript from sequencer... ")
+	bt, err := client.GetCurrentTranscript(cmd.Context())
+	if err != nil {
+		return nil, "", fmt.Errorf("get current transcript: %s", err)
+	}
+	fmt.Printf("OK\n")
+	return bt, fmt.Sprintf("%s (pulled at %s)", sequencerURL, time.Now().UTC().Format(time.RFC3339)), nil
+}
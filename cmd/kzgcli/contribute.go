@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/eip712"
 	"github.com/jsign/go-kzg-ceremony-client/extrand"
 	"github.com/jsign/go-kzg-ceremony-client/sequencerclient"
 	"github.com/spf13/cobra"
@@ -17,8 +23,11 @@ import (
 const (
 	tryContributeAttemptDelay  = time.Second * 30
 	sendContributionRetryDelay = time.Second
+	abortTimeout               = time.Second * 10
 )
 
+var errInterrupted = errors.New("interrupted")
+
 var contributeCmd = &cobra.Command{
 	Use:   "contribute",
 	Short: "Joins the lobby, waits for a contribution turn, and contributes to the ceremony",
@@ -31,6 +40,38 @@ var contributeCmd = &cobra.Command{
 			log.Fatalf("the session id can't be empty")
 		}
 
+		workDir, err := cmd.Flags().GetString("work-dir")
+		if err != nil {
+			log.Fatalf("get --work-dir flag value: %s", err)
+		}
+		resume, err := cmd.Flags().GetBool("resume")
+		if err != nil {
+			log.Fatalf("get --resume flag value: %s", err)
+		}
+		journal, err := openContributeJournal(workDir, sessionID, resume)
+		if err != nil {
+			log.Fatalf("opening contribution journal: %s", err)
+		}
+		if journal.State == stateReceipted {
+			fmt.Printf("We already contributed with this session, the receipt is in %s\n", journal.receiptPath())
+			return
+		}
+
+		identity, err := cmd.Flags().GetString("identity")
+		if err != nil {
+			log.Fatalf("get --identity flag value: %s", err)
+		}
+		if identity != "" {
+			if err := contribution.ValidateIdentity(identity); err != nil {
+				log.Fatalf("invalid identity: %s", err)
+			}
+		}
+
+		ecdsaKey, err := loadECDSAKey(cmd, identity)
+		if err != nil {
+			log.Fatalf("loading ethereum key: %s", err)
+		}
+
 		var extRandomness [][]byte
 		drand, err := cmd.Flags().GetBool("drand")
 		if err != nil {
@@ -60,75 +101,294 @@ var contributeCmd = &cobra.Command{
 			extRandomness = append(extRandomness, urlBytes)
 		}
 
-		sequencerURL, err := cmd.Flags().GetString("sequencer-url")
+		params, err := getCeremonyParameters(cmd)
 		if err != nil {
-			log.Fatalf("get --sequencer-url flag value: %s", err)
+			log.Fatalf("%s", err)
 		}
 
-		client, err := sequencerclient.New(sequencerURL)
+		client, err := newSequencerClient(cmd, sequencerclient.WithRetryPolicy(sequencerRetryPolicy))
 		if err != nil {
 			log.Fatalf("creating sequencer client: %s", err)
 		}
 
-		if err := contributeToCeremony(cmd.Context(), client, sessionID, extRandomness); err != nil {
+		if err := contributeToCeremony(cmd.Context(), client, params, journal, identity, ecdsaKey, extRandomness); err != nil {
 			log.Fatalf("contributing to ceremony: %s", err)
 		}
 		fmt.Printf("Success!\n")
 	},
 }
 
-func contributeToCeremony(ctx context.Context, client *sequencerclient.Client, sessionID string, extRandomness [][]byte) error {
+func contributeToCeremony(ctx context.Context, client *sequencerclient.Client, params []contribution.SubCeremonyParameters, journal *contributeJournal, identity string, ecdsaKey *ecdsa.PrivateKey, extRandomness [][]byte) error {
+	defer wipeSecrets(ecdsaKey, extRandomness)
+	sessionID := journal.SessionID
+
+	// A contribution computed before a crash is sent again if it still extends the current state when we get
+	// our turn, which is usually the same one if we're restarted before the sequencer compute deadline.
+	var computedBatch *contribution.BatchContribution
+	resumedState := journal.State
+	if resumedState == stateComputed || resumedState == stateSubmitted {
+		fmt.Printf("Loading our %s contribution %s... ", resumedState, journal.contributionPath())
+		bc, err := journal.loadContribution()
+		if err != nil {
+			return err
+		}
+		computedBatch = bc
+		fmt.Printf("OK\n")
+	}
+
+	// On SIGINT or SIGTERM the context is cancelled, so we can release our turn (if we have it) before exiting.
+	// After stop is called, a second signal kills the process as usual.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Enter the lobby and wait for our turn.
 	var contributionBatch *contribution.BatchContribution
 	for {
 		fmt.Printf("Waiting for our turn to contribute...\n")
 		cb, ok, err := client.TryContribute(ctx, sessionID)
+		if ctx.Err() != nil {
+			// The sequencer might have given us the turn before the request was cancelled.
+			stop()
+			abortContribution(client, sessionID)
+			return errInterrupted
+		}
+		var contributionErr *sequencerclient.ContributionError
+		if resumedState == stateSubmitted && errors.As(err, &contributionErr) {
+			return fmt.Errorf("waiting for our turn: %w (the sequencer might have accepted our contribution before the restart, check it with `kzgcli check-inclusion --contribution %s`)", err, journal.contributionPath())
+		}
 		if err != nil {
-			fmt.Printf("%v Waiting for our turn failed (err: %s), retrying in %v...\n", time.Now().Format("2006-01-02 15:04:05"), err, tryContributeAttemptDelay)
-			time.Sleep(tryContributeAttemptDelay)
-			continue
+			return fmt.Errorf("waiting for our turn: %w", err)
 		}
 		if !ok {
 			fmt.Printf("%v Still isn't our turn, waiting %v for retrying...\n", time.Now().Format("2006-01-02 15:04:05"), tryContributeAttemptDelay)
-			time.Sleep(tryContributeAttemptDelay)
+			select {
+			case <-time.After(tryContributeAttemptDelay):
+			case <-ctx.Done():
+				return errInterrupted
+			}
 			continue
 		}
 		contributionBatch = cb
 		break
 	}
 
-	// Contribute in our turn.
-	fmt.Printf("It's our turn! Contributing...\n")
-	now := time.Now()
-	if err := contributionBatch.Contribute(extRandomness...); err != nil {
-		log.Fatalf("failed on calculating contribution: %s", err)
+	// Don't contribute to a state that isn't of the expected ceremony.
+	if err := contributionBatch.ParameterCheck(params); err != nil {
+		abortContribution(client, sessionID)
+		return fmt.Errorf("the current state doesn't match the ceremony parameters: %s", err)
+	}
+
+	if computedBatch != nil {
+		fmt.Printf("It's our turn! Checking that our computed contribution extends the current state... ")
+		if ok, err := computedBatch.Verify(contributionBatch); err != nil || !ok {
+			fmt.Printf("no, it's stale\n")
+			computedBatch = nil
+		} else {
+			fmt.Printf("OK\n")
+			contributionBatch = computedBatch
+		}
+	}
+	if computedBatch == nil {
+		// Contribute in our turn.
+		if resumedState == stateComputed || resumedState == stateSubmitted {
+			fmt.Printf("Contributing again...\n")
+		} else {
+			fmt.Printf("It's our turn! Contributing...\n")
+		}
+		now := time.Now()
+		computed := make(chan error, 1)
+		go func() {
+			computed <- computeContribution(contributionBatch, identity, ecdsaKey, extRandomness)
+		}()
+		select {
+		case err := <-computed:
+			if err != nil {
+				abortContribution(client, sessionID)
+				return err
+			}
+		case <-ctx.Done():
+			stop()
+			abortContribution(client, sessionID)
+			// The secrets are wiped when computing the contribution finishes, so wait for it before exiting.
+			<-computed
+			return errInterrupted
+		}
+		fmt.Printf("Contribution ready, took %.02fs\n", time.Since(now).Seconds())
+
+		// The contribution is saved before sending it, so it can be sent again if we crash.
+		if err := journal.saveContribution(contributionBatch); err != nil {
+			abortContribution(client, sessionID)
+			return err
+		}
 	}
-	fmt.Printf("Contribution ready, took %.02fs\n", time.Since(now).Seconds())
 
 	// Send the contribution to the sequencer.
-	var contributionReceipt *sequencerclient.ContributionReceipt
-	for {
-		var err error
-		fmt.Printf("Sending contribution...\n")
-		contributionReceipt, err = client.Contribute(ctx, sessionID, contributionBatch)
+	if err := journal.transition(stateSubmitted); err != nil {
+		abortContribution(client, sessionID)
+		return err
+	}
+	fmt.Printf("Sending contribution...\n")
+	contributionReceipt, err := client.Contribute(ctx, sessionID, contributionBatch)
+	if errors.Is(err, sequencerclient.ErrContributionUnconfirmed) {
+		// Our turn isn't aborted, since the sequencer might be applying our contribution.
+		return fmt.Errorf("sending contribution: %w. Check if it was included with `kzgcli check-inclusion --contribution %s`, and if it wasn't, send it again with `kzgcli contribute --resume`", err, journal.contributionPath())
+	}
+	if err != nil {
+		interrupted := ctx.Err() != nil
+		stop()
+		abortContribution(client, sessionID)
+		if interrupted {
+			return errInterrupted
+		}
+		return fmt.Errorf("sending contribution: %w", err)
+	}
+	if err := journal.saveReceipt(contributionReceipt); err != nil {
+		return err
+	}
+
+	checkContributionReceipt(ctx, client, journal, identity, contributionReceipt, contributionBatch)
+
+	return nil
+}
+
+// computeContribution contributes to the batch contribution and signs it with the Ethereum key (if any).
+func computeContribution(bc *contribution.BatchContribution, identity string, ecdsaKey *ecdsa.PrivateKey, extRandomness [][]byte) error {
+	if err := bc.Contribute(identity, extRandomness...); err != nil {
+		return fmt.Errorf("failed on calculating contribution: %s", err)
+	}
+	if ecdsaKey != nil {
+		if err := eip712.SignBatchContribution(ecdsaKey, bc); err != nil {
+			return fmt.Errorf("failed on signing contribution: %s", err)
+		}
+	}
+	return nil
+}
+
+// abortContribution releases our turn to contribute so the lobby isn't locked until the sequencer compute
+// deadline. It uses its own context since it's usually called after ours is cancelled.
+func abortContribution(client *sequencerclient.Client, sessionID string) {
+	fmt.Printf("Releasing our contribution turn... ")
+	ctx, cancel := context.WithTimeout(context.Background(), abortTimeout)
+	defer cancel()
+	if err := client.Abort(ctx, sessionID); err != nil {
+		fmt.Printf("failed (err: %s)\n", err)
+		return
+	}
+	fmt.Printf("OK\n")
+}
+
+// wipeSecrets zeroes the in-memory Ethereum key and external entropy. The sub-ceremony secrets are already
+// wiped by the contribution when it finishes.
+func wipeSecrets(ecdsaKey *ecdsa.PrivateKey, extRandomness [][]byte) {
+	if ecdsaKey != nil {
+		d := ecdsaKey.D.Bits()
+		for i := range d {
+			d[i] = 0
+		}
+		ecdsaKey.D.SetInt64(0)
+	}
+	for _, b := range extRandomness {
+		for i := range b {
+			b[i] = 0
+		}
+	}
+}
+
+// sequencerRetryPolicy retries the temporary sequencer errors while contributing with an exponential backoff,
+// without a limit of attempts as the old retry loops did. Other errors (e.g: an expired session or a rejected
+// contribution) can't be fixed by retrying, so they're returned.
+var sequencerRetryPolicy = printRetryPolicy{&sequencerclient.ExponentialBackoff{
+	InitialDelay: sendContributionRetryDelay,
+	MaxDelay:     tryContributeAttemptDelay,
+}}
+
+// printRetryPolicy prints every retry of the wrapped retry policy.
+type printRetryPolicy struct {
+	sequencerclient.RetryPolicy
+}
+
+func (p printRetryPolicy) Retry(attempt int, err error) (time.Duration, bool) {
+	delay, ok := p.RetryPolicy.Retry(attempt, err)
+	if ok {
+		fmt.Printf("%v Request to the sequencer failed (err: %s), retrying in %v...\n", time.Now().Format("2006-01-02 15:04:05"), err, delay.Round(time.Millisecond))
+	}
+	return delay, ok
+}
+
+// checkContributionReceipt verifies the receipt against the sequencer address and the submitted contribution.
+// A bad receipt is the evidence of a misbehaving sequencer, so it's loudly reported but isn't fatal since the
+// contribution was already accepted.
+func checkContributionReceipt(ctx context.Context, client *sequencerclient.Client, journal *contributeJournal, identity string, contributionReceipt *sequencerclient.ContributionReceipt, bc *contribution.BatchContribution) {
+	fmt.Printf("Verifying contribution receipt... ")
+	status, err := client.GetStatus(ctx)
+	if err != nil {
+		fmt.Printf("failed\nCouldn't get the sequencer address to verify the receipt (err: %s), check it later with the saved receipt file\n", err)
+		return
+	}
+	receipt, err := contributionReceipt.Verify(status.SequencerAddress, bc)
+	if err == nil && identity != "" && !strings.EqualFold(receipt.Identity, identity) {
+		err = fmt.Errorf("the receipt identity is %s instead of %s", receipt.Identity, identity)
+	}
+	if err != nil {
+		fmt.Printf("FAILED\n\n")
+		fmt.Printf("!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!\n")
+		fmt.Printf("WARNING: the contribution receipt isn't valid: %s\n", err)
+		fmt.Printf("The sequencer (address %s) might be misbehaving. Keep %s\n", status.SequencerAddress, journal.receiptPath())
+		fmt.Printf("and %s as evidence, and report it to the ceremony coordinators.\n", journal.contributionPath())
+		fmt.Printf("!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!\n\n")
+		return
+	}
+	fmt.Printf("OK (signed by sequencer %s for %s)\n", status.SequencerAddress, receipt.Identity)
+}
+
+// loadECDSAKey loads the Ethereum key provided with the --eth-keystore or --eth-key-file flags (if any), and
+// checks that it matches the address of the identity.
+func loadECDSAKey(cmd *cobra.Command, identity string) (*ecdsa.PrivateKey, error) {
+	keystorePath, err := cmd.Flags().GetString("eth-keystore")
+	if err != nil {
+		return nil, fmt.Errorf("get --eth-keystore flag value: %s", err)
+	}
+	keyFilePath, err := cmd.Flags().GetString("eth-key-file")
+	if err != nil {
+		return nil, fmt.Errorf("get --eth-key-file flag value: %s", err)
+	}
+
+	var key *ecdsa.PrivateKey
+	switch {
+	case keystorePath != "" && keyFilePath != "":
+		return nil, fmt.Errorf("only one of --eth-keystore or --eth-key-file can be provided")
+	case keystorePath != "":
+		passwordFilePath, err := cmd.Flags().GetString("eth-keystore-password-file")
 		if err != nil {
-			fmt.Printf("Failed sending contribution!: %s\n", err)
-			fmt.Printf("Retrying sending contribution in %v\n", sendContributionRetryDelay)
-			time.Sleep(sendContributionRetryDelay)
-			continue
+			return nil, fmt.Errorf("get --eth-keystore-password-file flag value: %s", err)
 		}
-		break
+		var password string
+		if passwordFilePath != "" {
+			passwordBytes, err := os.ReadFile(passwordFilePath)
+			if err != nil {
+				return nil, fmt.Errorf("reading password file: %s", err)
+			}
+			password = strings.TrimRight(string(passwordBytes), "\r\n")
+		}
+		if key, err = eip712.LoadKeystoreKey(keystorePath, password); err != nil {
+			return nil, fmt.Errorf("loading keystore key: %s", err)
+		}
+	case keyFilePath != "":
+		if key, err = eip712.LoadHexKey(keyFilePath); err != nil {
+			return nil, fmt.Errorf("loading hex key: %s", err)
+		}
+	default:
+		return nil, nil
 	}
 
-	// Persist the receipt and contribution.
-	receiptJSON, _ := json.Marshal(contributionReceipt)
-	if err := os.WriteFile(fmt.Sprintf("contribution_receipt_%s.json", sessionID), receiptJSON, os.ModePerm); err != nil {
-		log.Fatalf("failed to save the contribution receipt (err: %s), printing to stdout as last resort: %s", err, receiptJSON)
+	// Only Ethereum identities can sign their contribution, and the key must match the identity address.
+	address, ok := eip712.AddressFromIdentity(identity)
+	if !ok {
+		return nil, fmt.Errorf("an ethereum key requires an --identity of the form eth|0x<address>")
 	}
-	ourContributionBatchJSON, _ := contribution.Encode(contributionBatch, true)
-	if err := os.WriteFile(fmt.Sprintf("my_contribution_%s.json", sessionID), ourContributionBatchJSON, os.ModePerm); err != nil {
-		log.Fatalf("failed to save the contribution (err: %s), printing to stdout as last resort: %s", err, ourContributionBatchJSON)
+	if keyAddress := crypto.PubkeyToAddress(key.PublicKey); keyAddress != address {
+		return nil, fmt.Errorf("the key address %s doesn't match the identity address %s", keyAddress.Hex(), address.Hex())
 	}
 
-	return nil
+	return key, nil
 }
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/transcript"
+	"github.com/spf13/cobra"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert <input-path> <output-path>",
+	Short: "Converts a batch contribution or transcript file between the JSON and binary formats",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			log.Fatalf("two arguments expected")
+		}
+		to, err := cmd.Flags().GetString("to")
+		if err != nil {
+			log.Fatalf("get --to flag value: %s", err)
+		}
+		uncompressed, err := cmd.Flags().GetBool("uncompressed")
+		if err != nil {
+			log.Fatalf("get --uncompressed flag value: %s", err)
+		}
+
+		isTranscript, err := isTranscriptFile(args[0])
+		if err != nil {
+			log.Fatalf("detecting file kind: %s", err)
+		}
+
+		fmt.Printf("Opening and parsing %s... ", args[0])
+		var (
+			bc       *contribution.BatchContribution
+			bt       *transcript.BatchTranscript
+			isBinary bool
+		)
+		if isTranscript {
+			bt, isBinary, err = readTranscriptFile(args[0])
+		} else {
+			bc, isBinary, err = readBatchContributionFile(args[0])
+		}
+		if err != nil {
+			log.Fatalf("reading input file: %s", err)
+		}
+		fmt.Printf("OK\n")
+
+		var toBinary bool
+		switch to {
+		case "":
+			toBinary = !isBinary
+		case "json":
+		case "binary":
+			toBinary = true
+		default:
+			log.Fatalf("unknown format %s, it must be json or binary", to)
+		}
+		if uncompressed && !toBinary {
+			log.Fatalf("--uncompressed is only supported for the binary format")
+		}
+
+		fmt.Printf("Encoding and saving to %s... ", args[1])
+		var encoded []byte
+		switch {
+		case isTranscript && toBinary:
+			encoded, err = transcript.EncodeBinary(bt, uncompressed)
+		case isTranscript:
+			var buf bytes.Buffer
+			err = transcript.Encode(&buf, bt, true)
+			encoded = buf.Bytes()
+		case toBinary:
+			encoded, err = contribution.EncodeBinary(bc, uncompressed)
+		default:
+			encoded, err = contribution.Encode(bc, true)
+		}
+		if err != nil {
+			log.Fatalf("encoding output file: %s", err)
+		}
+		if err := os.WriteFile(args[1], encoded, os.ModePerm); err != nil {
+			log.Fatalf("writing output file to %s: %s", args[1], err)
+		}
+		fmt.Printf("OK\n")
+	},
+}
+
+// readBatchContributionFile reads a batch contribution file detecting if it's in the JSON or binary format.
+func readBatchContributionFile(path string) (*contribution.BatchContribution, bool, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("reading file: %s", err)
+	}
+	if contribution.IsBinary(bytes) {
+		bc, err := contribution.DecodeBinaryBatchContribution(bytes)
+		if err != nil {
+			return nil, false, fmt.Errorf("decoding binary file: %s", err)
+		}
+		return bc, true, nil
+	}
+	bc, err := contribution.DecodeBatchContribution(bytes)
+	if err != nil {
+		return nil, false, fmt.Errorf("decoding json file: %s", err)
+	}
+	return bc, false, nil
+}
+
+// binaryMagicSize is the size of the magic bytes which start the binary containers.
+const binaryMagicSize = 4
+
+// readTranscriptFile reads a batch transcript file detecting if it's in the JSON or binary format. JSON files
+// are decoded while streamed.
+func readTranscriptFile(path string) (*transcript.BatchTranscript, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("opening file: %s", err)
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	// Peek fails if the file is shorter, but then it isn't a binary container anyway.
+	magic, _ := r.Peek(binaryMagicSize)
+	if transcript.IsBinary(magic) {
+		bytes, err := io.ReadAll(r)
+		if err != nil {
+			return nil, false, fmt.Errorf("reading file: %s", err)
+		}
+		bt, err := transcript.DecodeBinary(bytes)
+		if err != nil {
+			return nil, false, fmt.Errorf("decoding binary file: %s", err)
+		}
+		return bt, true, nil
+	}
+	bt, err := transcript.Decode(r)
+	if err != nil {
+		return nil, false, fmt.Errorf("decoding json file: %s", err)
+	}
+	return bt, false, nil
+}
+
+// isTranscriptFile returns true if the file is a batch transcript, or false if it's a batch contribution. JSON
+// files are told apart by their first known top-level field.
+func isTranscriptFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("opening file: %s", err)
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	magic, _ := r.Peek(binaryMagicSize)
+	switch {
+	case transcript.IsBinary(magic):
+		return true, nil
+	case contribution.IsBinary(magic):
+		return false, nil
+	}
+
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return false, fmt.Errorf("the file isn't a JSON object nor a binary container")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return false, fmt.Errorf("decoding json file: %s", err)
+		}
+		switch token {
+		case "transcripts", "participantIds", "participantEcdsaSignatures":
+			return true, nil
+		case "contributions", "ecdsaSignature":
+			return false, nil
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return false, fmt.Errorf("decoding json file: %s", err)
+		}
+	}
+	return false, fmt.Errorf("the file isn't a batch contribution nor a transcript")
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jsign/go-kzg-ceremony-client/transcript"
+	"github.com/spf13/cobra"
+)
+
+var inclusionProofCmd = &cobra.Command{
+	Use:   "inclusion-proof",
+	Short: "Contains commands to export and verify standalone inclusion proofs of a participant",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Usage(); err != nil {
+			log.Fatalf("cmd usage failed: %s", err)
+		}
+	},
+}
+
+var inclusionProofExportCmd = &cobra.Command{
+	Use:   "export <path>",
+	Short: "Exports the inclusion proof of a participant in the current sequencer transcript (or a transcript file)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		identity, err := cmd.Flags().GetString("identity")
+		if err != nil {
+			log.Fatalf("get --identity flag value: %s", err)
+		}
+		contributionPath, err := cmd.Flags().GetString("contribution")
+		if err != nil {
+			log.Fatalf("get --contribution flag value: %s", err)
+		}
+		if identity == "" && contributionPath == "" {
+			log.Fatalf("the --identity or --contribution flag is required")
+		}
+		transcriptPath, err := cmd.Flags().GetString("transcript")
+		if err != nil {
+			log.Fatalf("get --transcript flag value: %s", err)
+		}
+
+		batchTranscript, _, err := loadTranscript(cmd, transcriptPath)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+
+		fmt.Printf("Looking for the participant in the transcript... ")
+		var index int
+		if contributionPath != "" {
+			bc, _, err := readBatchContributionFile(contributionPath)
+			if err != nil {
+				log.Fatalf("reading contribution file: %s", err)
+			}
+			inclusion, err := batchTranscript.CheckInclusion(bc, identity)
+			if err != nil {
+				log.Fatalf("the contribution isn't correctly included in the transcript: %s", err)
+			}
+			index = inclusion.Index
+		} else {
+			for j := 1; j < len(batchTranscript.ParticipantIDs); j++ {
+				if strings.EqualFold(batchTranscript.ParticipantIDs[j], identity) {
+					index = j
+					break
+				}
+			}
+			if index == 0 {
+				log.Fatalf("%s isn't a participant of the transcript", identity)
+			}
+		}
+		fmt.Printf("OK (index %d)\n", index)
+
+		fmt.Printf("Building inclusion proof... ")
+		proof, err := batchTranscript.InclusionProof(index)
+		if err != nil {
+			log.Fatalf("building inclusion proof: %s", err)
+		}
+		if err := proof.Verify(); err != nil {
+			log.Fatalf("the participant contribution isn't valid: %s", err)
+		}
+		proofJSON, err := transcript.EncodeInclusionProof(proof, true)
+		if err != nil {
+			log.Fatalf("encoding inclusion proof: %s", err)
+		}
+		if err := os.WriteFile(args[0], proofJSON, os.ModePerm); err != nil {
+			log.Fatalf("saving inclusion proof: %s", err)
+		}
+		fmt.Printf("OK\n")
+		fmt.Printf("Saved the inclusion proof of %s to %s, for the transcript with commitment 0x%x\n", proof.Participant.ParticipantID, args[0], proof.Commitment)
+	},
+}
+
+var inclusionProofVerifyCmd = &cobra.Command{
+	Use:   "verify <path>",
+	Short: "Verifies an inclusion proof offline against a transcript commitment",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		commitmentHex, err := cmd.Flags().GetString("commitment")
+		if err != nil {
+			log.Fatalf("get --commitment flag value: %s", err)
+		}
+		transcriptPath, err := cmd.Flags().GetString("transcript")
+		if err != nil {
+			log.Fatalf("get --transcript flag value: %s", err)
+		}
+		if commitmentHex != "" && transcriptPath != "" {
+			log.Fatalf("only one of --commitment or --transcript can be provided")
+		}
+
+		fmt.Printf("Reading inclusion proof... ")
+		proofJSON, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("reading inclusion proof file: %s", err)
+		}
+		proof, err := transcript.DecodeInclusionProof(proofJSON)
+		if err != nil {
+			log.Fatalf("decoding inclusion proof: %s", err)
+		}
+		fmt.Printf("OK\n")
+
+		fmt.Printf("Verifying inclusion proof... ")
+		if err := proof.Verify(); err != nil {
+			log.Fatalf("the inclusion proof isn't valid: %s", err)
+		}
+		fmt.Printf("OK\n")
+
+		// The proof is only meaningful for a commitment of a trusted transcript.
+		var expected []byte
+		switch {
+		case commitmentHex != "":
+			expected, err = hex.DecodeString(strings.TrimPrefix(commitmentHex, "0x"))
+			if err != nil {
+				log.Fatalf("decoding --commitment: %s", err)
+			}
+		case transcriptPath != "":
+			fmt.Printf("Calculating transcript file commitment... ")
+			batchTranscript, _, err := readTranscriptFile(transcriptPath)
+			if err != nil {
+				log.Fatalf("reading transcript file: %s", err)
+			}
+			commitment, err := batchTranscript.Commitment()
+			if err != nil {
+				log.Fatalf("calculating transcript commitment: %s", err)
+			}
+			expected = commitment[:]
+			fmt.Printf("OK\n")
+		}
+		if expected != nil && string(expected) != string(proof.Commitment[:]) {
+			log.Fatalf("the inclusion proof is for the transcript with commitment 0x%x, but 0x%x was expected", proof.Commitment, expected)
+		}
+
+		fmt.Printf("%s contributed at index %d of %d to the transcript with commitment 0x%x.\n", proof.Participant.ParticipantID, proof.Index, proof.NumParticipants-1, proof.Commitment)
+		if expected == nil {
+			fmt.Printf("Check that the commitment is of a trusted transcript, or provide it with --commitment or --transcript.\n")
+		}
+	},
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/sequencerclient"
+)
+
+// contributeState is a state of the contribute workflow, which is journaled so it can be resumed after a crash.
+type contributeState string
+
+const (
+	// stateWaiting is waiting in the lobby for our turn.
+	stateWaiting contributeState = "waiting"
+	// stateComputed is after the contribution was computed and saved, but before sending it.
+	stateComputed contributeState = "computed"
+	// stateSubmitted is after the contribution was sent, but before saving the receipt.
+	stateSubmitted contributeState = "submitted"
+	// stateReceipted is after the sequencer accepted the contribution and the receipt was saved.
+	stateReceipted contributeState = "receipted"
+)
+
+// contributeJournal is the journal of the contribute workflow of a session. It's stored in the work directory
+// with the contribution and receipt files of the session.
+type contributeJournal struct {
+	dir string
+
+	SessionID string          `json:"sessionId"`
+	State     contributeState `json:"state"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// openContributeJournal opens the journal of the session in the work directory. If there's no journal, a new
+// one in the waiting state is created unless we're resuming. A computed or submitted contribution can only be
+// continued if we're resuming, so it's never silently replaced by a new one.
+func openContributeJournal(workDir string, sessionID string, resume bool) (*contributeJournal, error) {
+	j := &contributeJournal{dir: workDir, SessionID: sessionID}
+	journalJSON, err := os.ReadFile(j.path())
+	if errors.Is(err, os.ErrNotExist) {
+		if resume {
+			return nil, fmt.Errorf("there's no contribution to resume for this session in %s", workDir)
+		}
+		if err := os.MkdirAll(workDir, 0o700); err != nil {
+			return nil, fmt.Errorf("creating work directory: %s", err)
+		}
+		if err := j.transition(stateWaiting); err != nil {
+			return nil, err
+		}
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading journal: %s", err)
+	}
+	if err := json.Unmarshal(journalJSON, j); err != nil {
+		return nil, fmt.Errorf("decoding journal %s: %s", j.path(), err)
+	}
+	if j.SessionID != sessionID {
+		return nil, fmt.Errorf("the journal %s is of another session", j.path())
+	}
+	switch j.State {
+	case stateWaiting, stateReceipted:
+	case stateComputed, stateSubmitted:
+		if !resume {
+			return nil, fmt.Errorf("there's a %s contribution for this session in %s, continue it with --resume", j.State, workDir)
+		}
+	default:
+		return nil, fmt.Errorf("the journal %s has an unknown state %s", j.path(), j.State)
+	}
+
+	return j, nil
+}
+
+func (j *contributeJournal) path() string {
+	return filepath.Join(j.dir, fmt.Sprintf("contribute_journal_%s.json", j.SessionID))
+}
+
+func (j *contributeJournal) contributionPath() string {
+	return filepath.Join(j.dir, fmt.Sprintf("my_contribution_%s.json", j.SessionID))
+}
+
+func (j *contributeJournal) receiptPath() string {
+	return filepath.Join(j.dir, fmt.Sprintf("contribution_receipt_%s.json", j.SessionID))
+}
+
+// transition saves the journal with the new state.
+func (j *contributeJournal) transition(state contributeState) error {
+	j.State = state
+	j.UpdatedAt = time.Now().UTC()
+	journalJSON, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding journal: %s", err)
+	}
+	if err := writeFileAtomic(j.path(), journalJSON); err != nil {
+		return fmt.Errorf("saving journal: %s", err)
+	}
+	return nil
+}
+
+// saveContribution saves the computed contribution and moves to the computed state.
+func (j *contributeJournal) saveContribution(bc *contribution.BatchContribution) error {
+	bcJSON, err := contribution.Encode(bc, true)
+	if err != nil {
+		return fmt.Errorf("encoding contribution: %s", err)
+	}
+	if err := writeFileAtomic(j.contributionPath(), bcJSON); err != nil {
+		return fmt.Errorf("saving contribution: %s", err)
+	}
+	return j.transition(stateComputed)
+}
+
+// loadContribution loads the saved computed contribution.
+func (j *contributeJournal) loadContribution() (*contribution.BatchContribution, error) {
+	bc, _, err := readBatchContributionFile(j.contributionPath())
+	if err != nil {
+		return nil, fmt.Errorf("reading saved contribution: %s", err)
+	}
+	return bc, nil
+}
+
+// saveReceipt saves the receipt and moves to the receipted state.
+func (j *contributeJournal) saveReceipt(receipt *sequencerclient.ContributionReceipt) error {
+	receiptJSON, err := json.Marshal(receipt)
+	if err != nil {
+		return fmt.Errorf("encoding receipt: %s", err)
+	}
+	if err := writeFileAtomic(j.receiptPath(), receiptJSON); err != nil {
+		return fmt.Errorf("failed to save the contribution receipt (err: %s), printing to stdout as last resort: %s", err, receiptJSON)
+	}
+	return j.transition(stateReceipted)
+}
+
+// writeFileAtomic writes the data to a synced temporary file in the same directory, which is renamed to path.
+// After a crash, path has either its previous content or the new one, but never a partial write.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %s", err)
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("writing temporary file: %s", err)
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return fmt.Errorf("changing temporary file permissions: %s", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing temporary file: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %s", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("renaming temporary file: %s", err)
+	}
+
+	// Sync the directory so the rename survives a crash. Not every platform supports it, so it's best effort.
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/sequencer"
+	"github.com/jsign/go-kzg-ceremony-client/sequencerclient"
+	"github.com/stretchr/testify/require"
+)
+
+var testParameters = []contribution.SubCeremonyParameters{{NumG1Powers: 16, NumG2Powers: 5}, {NumG1Powers: 32, NumG2Powers: 5}}
+
+func TestOpenContributeJournal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		state       contributeState
+		sessionID   string
+		resume      bool
+		expectedErr string
+	}{
+		{name: "new"},
+		{name: "no journal with resume", resume: true, expectedErr: "there's no contribution to resume"},
+		{name: "waiting", state: stateWaiting},
+		{name: "waiting with resume", state: stateWaiting, resume: true},
+		{name: "computed", state: stateComputed, expectedErr: "continue it with --resume"},
+		{name: "computed with resume", state: stateComputed, resume: true},
+		{name: "submitted", state: stateSubmitted, expectedErr: "continue it with --resume"},
+		{name: "submitted with resume", state: stateSubmitted, resume: true},
+		{name: "receipted", state: stateReceipted},
+		{name: "another session", state: stateComputed, sessionID: "other-session", resume: true, expectedErr: "is of another session"},
+		{name: "unknown state", state: "unknown", resume: true, expectedErr: "has an unknown state unknown"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			workDir := t.TempDir()
+			if test.state != "" {
+				sessionID := test.sessionID
+				if sessionID == "" {
+					sessionID = "session"
+				}
+				saved := &contributeJournal{dir: workDir, SessionID: sessionID}
+				require.NoError(t, saved.transition(test.state))
+				// The journal of another session is given the name of the opened one.
+				if sessionID != "session" {
+					opened := &contributeJournal{dir: workDir, SessionID: "session"}
+					require.NoError(t, os.Rename(saved.path(), opened.path()))
+				}
+			}
+
+			journal, err := openContributeJournal(workDir, "session", test.resume)
+			if test.expectedErr != "" {
+				require.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			expectedState := test.state
+			if expectedState == "" {
+				expectedState = stateWaiting
+			}
+			require.Equal(t, expectedState, journal.State)
+			require.FileExists(t, journal.path())
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.json")
+	require.NoError(t, writeFileAtomic(path, []byte("old content")))
+	require.NoError(t, writeFileAtomic(path, []byte("new")))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "new", string(content))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temporary files are left behind")
+}
+
+func TestResumeContribution(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		state contributeState
+		stale bool
+	}{
+		{name: "computed", state: stateComputed},
+		{name: "submitted", state: stateSubmitted},
+		{name: "stale computed", state: stateComputed, stale: true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			seq, err := sequencer.New(sequencer.Config{Parameters: testParameters})
+			require.NoError(t, err)
+			server := httptest.NewServer(seq.Handler())
+			t.Cleanup(server.Close)
+			client, err := sequencerclient.New(server.URL)
+			require.NoError(t, err)
+
+			// Compute and save a contribution, and crash before its receipt.
+			sessionID, err := seq.NewSession("git|1|alice")
+			require.NoError(t, err)
+			journal, err := openContributeJournal(t.TempDir(), sessionID, false)
+			require.NoError(t, err)
+			computed, err := seq.TryContribute(sessionID)
+			require.NoError(t, err)
+			require.NoError(t, computed.Contribute(""))
+			require.NoError(t, journal.saveContribution(computed))
+			require.NoError(t, journal.transition(test.state))
+			require.NoError(t, seq.Abort(sessionID))
+
+			// Another participant contributes before we're restarted, so our contribution doesn't extend the
+			// current state.
+			if test.stale {
+				otherSessionID, err := seq.NewSession("git|2|bob")
+				require.NoError(t, err)
+				bc, err := seq.TryContribute(otherSessionID)
+				require.NoError(t, err)
+				require.NoError(t, bc.Contribute(""))
+				_, err = seq.Contribute(otherSessionID, bc)
+				require.NoError(t, err)
+			}
+
+			journal, err = openContributeJournal(journal.dir, sessionID, true)
+			require.NoError(t, err)
+			require.NoError(t, contributeToCeremony(context.Background(), client, testParameters, journal, "", nil, nil))
+			require.Equal(t, stateReceipted, journal.State)
+			require.FileExists(t, journal.receiptPath())
+
+			batchTranscript := seq.Transcript()
+			require.NoError(t, batchTranscript.Verify())
+			for i, transcript := range batchTranscript.Transcripts {
+				potPubKeys := transcript.Witness.PotPubKeys
+				contributedPotPubKey := potPubKeys[len(potPubKeys)-1]
+				if test.stale {
+					require.Len(t, potPubKeys, 3)
+					require.False(t, contributedPotPubKey.Equal(&computed.Contributions[i].PotPubKey), "the stale contribution was sent")
+				} else {
+					require.Len(t, potPubKeys, 2)
+					require.True(t, contributedPotPubKey.Equal(&computed.Contributions[i].PotPubKey), "the computed contribution wasn't reused")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+
+	"github.com/jsign/go-kzg-ceremony-client/sequencerclient"
+	"github.com/spf13/cobra"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Logs in with the sequencer using a GitHub account or an Ethereum address to get a session id",
+	Run: func(cmd *cobra.Command, args []string) {
+		github, err := cmd.Flags().GetBool("github")
+		if err != nil {
+			log.Fatalf("get --github flag value: %s", err)
+		}
+		eth, err := cmd.Flags().GetBool("eth")
+		if err != nil {
+			log.Fatalf("get --eth flag value: %s", err)
+		}
+		if github && eth {
+			log.Fatalf("only one of --github or --eth can be provided")
+		}
+		noBrowser, err := cmd.Flags().GetBool("no-browser")
+		if err != nil {
+			log.Fatalf("get --no-browser flag value: %s", err)
+		}
+		listenAddr, err := cmd.Flags().GetString("callback-listen")
+		if err != nil {
+			log.Fatalf("get --callback-listen flag value: %s", err)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			log.Fatalf("get --timeout flag value: %s", err)
+		}
+		client, err := newSequencerClient(cmd)
+		if err != nil {
+			log.Fatalf("creating sequencer client: %s", err)
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		defer cancel()
+		session, err := client.Login(ctx, listenAddr, func(links sequencerclient.AuthLinks) error {
+			var link string
+			switch {
+			case github:
+				link = links.GithubAuthURL
+			case eth:
+				link = links.EthAuthURL
+			default:
+				fmt.Printf("Open one of the following links in your browser to login:\n")
+				fmt.Printf("- GitHub account: %s\n", links.GithubAuthURL)
+				fmt.Printf("- Ethereum address: %s\n", links.EthAuthURL)
+				fmt.Printf("Waiting for the login to finish (timeout %v)...\n", timeout)
+				return nil
+			}
+			if noBrowser || openBrowser(link) != nil {
+				fmt.Printf("Open the following link in your browser to login:\n%s\n", link)
+			} else {
+				fmt.Printf("Opened the login link in your browser, if it didn't open use the following one:\n%s\n", link)
+			}
+			fmt.Printf("Waiting for the login to finish (timeout %v)...\n", timeout)
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("login failed: %s", err)
+		}
+
+		account := session.Nickname
+		if account == "" {
+			account = session.Subject
+		}
+		if account != "" {
+			fmt.Printf("Logged in as %s", account)
+			if session.Provider != "" {
+				fmt.Printf(" (%s)", session.Provider)
+			}
+			fmt.Printf("\n")
+		}
+		fmt.Printf("Your session id is: %s\n", session.SessionID)
+		fmt.Printf("Contribute with: kzgcli contribute --session-id %s\n", session.SessionID)
+	},
+}
+
+// openBrowser opens the link with the default browser of the platform.
+func openBrowser(link string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", link)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", link)
+	default:
+		cmd = exec.Command("xdg-open", link)
+	}
+	return cmd.Start()
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/sequencer"
+	"github.com/jsign/go-kzg-ceremony-client/sequencerclient"
 	"github.com/spf13/cobra"
 )
 
@@ -42,22 +49,76 @@ var offlineCmd = &cobra.Command{
 func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 	rootCmd.PersistentFlags().String("sequencer-url", "https://seq.ceremony.ethereum.org", "The URL of the ceremony sequencer")
+	rootCmd.PersistentFlags().String("parameters", formatCeremonyParameters(contribution.CeremonyParameters), "Comma separated <numG1Powers>x<numG2Powers> of each sub-ceremony that states and transcripts must match")
+	rootCmd.PersistentFlags().Duration("http-timeout", 5*time.Minute, "The maximum duration of each request to the sequencer, including downloading the transcript (0 means no timeout)")
+	rootCmd.PersistentFlags().String("ca-bundle", "", "Path to a PEM file with CA certificates to trust for the sequencer in addition to the system ones (e.g: of a TLS-inspecting proxy)")
+	rootCmd.PersistentFlags().StringSlice("pin-sha256", nil, "Base64 SHA-256 hash of a public key (SPKI) that the sequencer certificate chain must have, can be repeated")
 
 	rootCmd.AddCommand(statusCmd)
 
+	// Authentication commands.
+	loginCmd.Flags().Bool("github", false, "Login with a GitHub account")
+	loginCmd.Flags().Bool("eth", false, "Login with an Ethereum address")
+	loginCmd.Flags().Bool("no-browser", false, "Print the login link instead of opening it in the browser")
+	loginCmd.Flags().String("callback-listen", "127.0.0.1:0", "The local address to listen for the sequencer redirect after the login")
+	loginCmd.Flags().Duration("timeout", 5*time.Minute, "The maximum time to wait for the login to finish")
+	rootCmd.AddCommand(loginCmd)
+
 	// Online contribution commands.
 	contributeCmd.Flags().String("session-id", "", "The sesion id as generated in the 'session_id' field in the authentication process")
 	contributeCmd.Flags().Bool("drand", false, "Pull entropy from the Drand network to be mixed with local CSRNG")
 	contributeCmd.Flags().String("urlrand", "", "Pull entropy from an HTTP endpoint mixed with local CSRNG")
+	contributeCmd.Flags().String("identity", "", "The participant identity (eth|0x<address> or git|<id>|<handle>) to BLS sign with the contribution secrets")
+	contributeCmd.Flags().String("work-dir", ".", "The directory where the contribution journal, the contribution and the receipt are saved")
+	contributeCmd.Flags().Bool("resume", false, "Resume a contribution journaled in --work-dir, sending the computed contribution again if it's still valid")
+	addECDSAKeyFlags(contributeCmd)
 	rootCmd.AddCommand(contributeCmd)
 
 	// Verification commands.
+	verifyTranscriptCmd.Flags().Bool("exhaustive", false, "Check each pairing equation independently instead of batching them with random linear combinations (much slower)")
+	verifyTranscriptCmd.Flags().Bool("check-bls", false, "Verify the BLS signatures of participant identities in the transcript")
+	verifyTranscriptCmd.Flags().String("format", "table", "The format of the verification report, table or json")
 	rootCmd.AddCommand(verifyTranscriptCmd)
+	checkInclusionCmd.Flags().String("contribution", "", "Path to our contribution file (e.g: my_contribution_<session-id>.json)")
+	checkInclusionCmd.Flags().String("transcript", "", "Path to a transcript file to check instead of pulling the current one from the sequencer")
+	checkInclusionCmd.Flags().String("identity", "", "The participant identity (eth|0x<address> or git|<id>|<handle>) expected for the contribution (default: any)")
+	rootCmd.AddCommand(checkInclusionCmd)
+	inclusionProofExportCmd.Flags().String("identity", "", "The participant identity (eth|0x<address> or git|<id>|<handle>) to export the inclusion proof of")
+	inclusionProofExportCmd.Flags().String("contribution", "", "Path to the participant contribution file, to find the participant by its contribution")
+	inclusionProofExportCmd.Flags().String("transcript", "", "Path to a transcript file to use instead of pulling the current one from the sequencer")
+	inclusionProofVerifyCmd.Flags().String("commitment", "", "Hex encoded commitment of a trusted transcript that the proof must match")
+	inclusionProofVerifyCmd.Flags().String("transcript", "", "Path to a trusted transcript file whose commitment the proof must match")
+	rootCmd.AddCommand(inclusionProofCmd)
+	inclusionProofCmd.AddCommand(inclusionProofExportCmd)
+	inclusionProofCmd.AddCommand(inclusionProofVerifyCmd)
 
 	// Offline commands.
 	offlineContributeCmd.Flags().String("urlrand", "", "Pull entropy from an HTTP endpoint mixed with local CSRNG")
 	offlineContributeCmd.Flags().String("hex-entropy", "", "Hex encoded entropy to be mixed with local CSRNG")
+	offlineContributeCmd.Flags().String("identity", "", "The participant identity (eth|0x<address> or git|<id>|<handle>) to BLS sign with the contribution secrets")
+	addECDSAKeyFlags(offlineContributeCmd)
+	offlineDownloadStateCmd.Flags().Bool("binary", false, "Save the current state in the binary format instead of JSON")
 	offlineSendContributionCmd.Flags().String("session-id", "", "The sesion id as generated in the 'session_id' field in the authentication process")
+	offlineSendContributionCmd.Flags().Bool("abort-if-stale", false, "Release our turn if the contribution doesn't extend the current state, instead of keeping it to send a new one")
+
+	// Format commands.
+	convertCmd.Flags().String("to", "", "The output format, json or binary (default: the opposite of the input format)")
+	convertCmd.Flags().Bool("uncompressed", false, "Store uncompressed points in the binary format, which is larger but faster to decode")
+	rootCmd.AddCommand(convertCmd)
+
+	// Test vectors commands.
+	testVectorsGenerateCmd.Flags().String("seed", "", "Hex encoded seed to derive the sub-ceremonies secrets")
+	testVectorsGenerateCmd.Flags().String("initial", "", "Path to the initial state file (default: generated from --parameters)")
+	rootCmd.AddCommand(testVectorsCmd)
+	testVectorsCmd.AddCommand(testVectorsGenerateCmd)
+
+	// Sequencer commands.
+	sequencerServeCmd.Flags().String("listen", "localhost:8080", "The address to listen for HTTP requests")
+	sequencerServeCmd.Flags().Duration("compute-deadline", sequencer.DefaultComputeDeadline, "The time a participant has to submit its contribution after getting its turn")
+	sequencerServeCmd.Flags().StringSlice("participant", nil, "Participant identity (eth|0x<address> or git|<id>|<handle>) to create a session for, can be repeated")
+	sequencerServeCmd.Flags().String("key-file", "", "Path to a file containing a raw hex Ethereum key to sign receipts (default: a random key)")
+	rootCmd.AddCommand(sequencerCmd)
+	sequencerCmd.AddCommand(sequencerServeCmd)
 
 	rootCmd.AddCommand(offlineCmd)
 	offlineCmd.AddCommand(offlineDownloadStateCmd)
@@ -65,6 +126,59 @@ func init() {
 	offlineCmd.AddCommand(offlineSendContributionCmd)
 }
 
+func addECDSAKeyFlags(cmd *cobra.Command) {
+	cmd.Flags().String("eth-keystore", "", "Path to an encrypted geth keystore file with the Ethereum key to EIP-712 sign the contribution")
+	cmd.Flags().String("eth-keystore-password-file", "", "Path to a file containing the password of the --eth-keystore file")
+	cmd.Flags().String("eth-key-file", "", "Path to a file containing a raw hex Ethereum key to EIP-712 sign the contribution")
+}
+
+// newSequencerClient returns a client of the --sequencer-url sequencer configured with the HTTP flags and the
+// extra options.
+func newSequencerClient(cmd *cobra.Command, extraOpts ...sequencerclient.Option) (*sequencerclient.Client, error) {
+	sequencerURL, err := cmd.Flags().GetString("sequencer-url")
+	if err != nil {
+		return nil, fmt.Errorf("get --sequencer-url flag value: %s", err)
+	}
+	timeout, err := cmd.Flags().GetDuration("http-timeout")
+	if err != nil {
+		return nil, fmt.Errorf("get --http-timeout flag value: %s", err)
+	}
+	opts := []sequencerclient.Option{
+		sequencerclient.WithTimeout(timeout),
+		sequencerclient.WithUserAgent("kzgcli"),
+	}
+
+	caBundle, err := cmd.Flags().GetString("ca-bundle")
+	if err != nil {
+		return nil, fmt.Errorf("get --ca-bundle flag value: %s", err)
+	}
+	if caBundle != "" {
+		pemCerts, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %s", err)
+		}
+		opts = append(opts, sequencerclient.WithCACertificates(pemCerts))
+	}
+
+	pins, err := cmd.Flags().GetStringSlice("pin-sha256")
+	if err != nil {
+		return nil, fmt.Errorf("get --pin-sha256 flag value: %s", err)
+	}
+	if len(pins) > 0 {
+		hashes := make([][sha256.Size]byte, len(pins))
+		for i, pin := range pins {
+			hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256//"))
+			if err != nil || len(hash) != sha256.Size {
+				return nil, fmt.Errorf("the pin %s isn't a base64 SHA-256 hash", pin)
+			}
+			copy(hashes[i][:], hash)
+		}
+		opts = append(opts, sequencerclient.WithPinnedSPKIs(hashes...))
+	}
+
+	return sequencerclient.New(sequencerURL, append(opts, extraOpts...)...)
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
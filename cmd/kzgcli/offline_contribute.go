@@ -3,24 +3,39 @@ package main
 import (
 	"encoding/hex"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"strings"
 
 	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/eip712"
 	"github.com/jsign/go-kzg-ceremony-client/extrand"
 	"github.com/spf13/cobra"
 )
 
 var offlineContributeCmd = &cobra.Command{
 	Use:   "contribute <path-current-state-file> <path-contribution-file>",
-	Short: "Opens a file with the current state of the ceremony, makes the contribution, and saves the new state to a file.",
+	Short: "Opens a file with the current state of the ceremony, makes the contribution, and saves the new state to a file in the same format.",
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 2 {
 			log.Fatalf("two arguments expected")
 		}
 
+		identity, err := cmd.Flags().GetString("identity")
+		if err != nil {
+			log.Fatalf("get --identity flag value: %s", err)
+		}
+		if identity != "" {
+			if err := contribution.ValidateIdentity(identity); err != nil {
+				log.Fatalf("invalid identity: %s", err)
+			}
+		}
+
+		ecdsaKey, err := loadECDSAKey(cmd, identity)
+		if err != nil {
+			log.Fatalf("loading ethereum key: %s", err)
+		}
+
 		urlrand, err := cmd.Flags().GetString("urlrand")
 		if err != nil {
 			log.Fatalf("get --urlrand flag value: %s", err)
@@ -48,28 +63,37 @@ var offlineContributeCmd = &cobra.Command{
 			extRandomness = append(extRandomness, hb)
 		}
 
-		fmt.Printf("Opening and parsing offline current state file...")
-		f, err := os.Open(args[0])
+		params, err := getCeremonyParameters(cmd)
 		if err != nil {
-			log.Fatalf("opening current state file at %s: %s", args[0], err)
+			log.Fatalf("%s", err)
 		}
-		defer f.Close()
 
-		bytes, err := io.ReadAll(f)
+		fmt.Printf("Opening and parsing offline current state file...")
+		contributionBatch, isBinary, err := readBatchContributionFile(args[0])
 		if err != nil {
-			log.Fatalf("reading current state file: %s", err)
+			log.Fatalf("reading current state file at %s: %s", args[0], err)
 		}
-		contributionBatch, err := contribution.DecodeBatchContribution(bytes)
-		if err != nil {
-			log.Fatalf("deserializing file content: %s", err)
+		if err := contributionBatch.ParameterCheck(params); err != nil {
+			log.Fatalf("the current state file doesn't match the ceremony parameters: %s", err)
 		}
 		fmt.Printf("OK\nCalculating contribution... ")
 
-		if err := contributionBatch.Contribute(extRandomness...); err != nil {
+		if err := contributionBatch.Contribute(identity, extRandomness...); err != nil {
 			log.Fatalf("failed on calculating contribution: %s", err)
 		}
+		if ecdsaKey != nil {
+			if err := eip712.SignBatchContribution(ecdsaKey, contributionBatch); err != nil {
+				log.Fatalf("failed on signing contribution: %s", err)
+			}
+		}
 
-		nbytes, err := contribution.Encode(contributionBatch, true)
+		// The contribution is saved in the same format as the current state file.
+		var nbytes []byte
+		if isBinary {
+			nbytes, err = contribution.EncodeBinary(contributionBatch, false)
+		} else {
+			nbytes, err = contribution.Encode(contributionBatch, true)
+		}
 		if err != nil {
 			log.Fatalf("encoding contribution: %s", err)
 		}
@@ -6,7 +6,6 @@ import (
 	"os"
 
 	"github.com/jsign/go-kzg-ceremony-client/contribution"
-	"github.com/jsign/go-kzg-ceremony-client/sequencerclient"
 	"github.com/spf13/cobra"
 )
 
@@ -17,11 +16,11 @@ var offlineDownloadStateCmd = &cobra.Command{
 		if len(args) != 1 {
 			log.Fatalf("one argument exected")
 		}
-		sequencerURL, err := cmd.Flags().GetString("sequencer-url")
+		binary, err := cmd.Flags().GetBool("binary")
 		if err != nil {
-			log.Fatalf("get --sequencer-url flag value: %s", err)
+			log.Fatalf("get --binary flag value: %s", err)
 		}
-		client, err := sequencerclient.New(sequencerURL)
+		client, err := newSequencerClient(cmd)
 		if err != nil {
 			log.Fatalf("creating sequencer client: %s", err)
 		}
@@ -43,9 +42,14 @@ var offlineDownloadStateCmd = &cobra.Command{
 		}
 
 		fmt.Printf("Encoding and saving to %s... ", args[0])
-		bytes, err := contribution.Encode(&bc, true)
+		var bytes []byte
+		if binary {
+			bytes, err = contribution.EncodeBinary(&bc, false)
+		} else {
+			bytes, err = contribution.Encode(&bc, true)
+		}
 		if err != nil {
-			log.Fatalf("encoding current state to json: %s", err)
+			log.Fatalf("encoding current state: %s", err)
 		}
 
 		if err := os.WriteFile(args[0], bytes, os.ModePerm); err != nil {
@@ -2,9 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/jsign/go-kzg-ceremony-client/contribution"
@@ -28,63 +31,125 @@ var offlineSendContributionCmd = &cobra.Command{
 			log.Fatalf("the session id can't be empty")
 		}
 
-		contributionBytes, err := os.ReadFile(args[0])
+		params, err := getCeremonyParameters(cmd)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+
+		contributionBatch, _, err := readBatchContributionFile(args[0])
 		if err != nil {
 			log.Fatalf("reading contribution file: %s", err)
 		}
-		contributionBatch, err := contribution.DecodeBatchContribution(contributionBytes)
+		if err := contributionBatch.ParameterCheck(params); err != nil {
+			log.Fatalf("the contribution file doesn't match the ceremony parameters: %s", err)
+		}
+		abortIfStale, err := cmd.Flags().GetBool("abort-if-stale")
 		if err != nil {
-			log.Fatalf("decoding contribution file: %s", err)
+			log.Fatalf("get --abort-if-stale flag value: %s", err)
 		}
 
-		sequencerURL, err := cmd.Flags().GetString("sequencer-url")
+		// A contribution with inconsistent powers can't be valid for any state, so there's no need to wait for
+		// our turn to reject it.
+		fmt.Printf("Checking the contribution file powers... ")
+		ok, err := contributionBatch.PowersCheck()
 		if err != nil {
-			log.Fatalf("get --sequencer-url flag value: %s", err)
+			log.Fatalf("checking the contribution file powers: %s", err)
+		}
+		if !ok {
+			log.Fatalf("the contribution file is invalid, its powers aren't consistent powers of the same tau")
 		}
-		client, err := sequencerclient.New(sequencerURL)
+		fmt.Printf("OK\n")
+
+		client, err := newSequencerClient(cmd, sequencerclient.WithRetryPolicy(sequencerRetryPolicy))
 		if err != nil {
 			log.Fatalf("creating sequencer client: %s", err)
 		}
 
+		// On SIGINT or SIGTERM the context is cancelled, so we can release our turn (if we have it) before exiting.
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		var currentState *contribution.BatchContribution
 		for {
-			_, ok, err := client.TryContribute(cmd.Context(), sessionID)
+			cb, ok, err := client.TryContribute(ctx, sessionID)
+			if ctx.Err() != nil {
+				stop()
+				abortContribution(client, sessionID)
+				log.Fatalf("%s", errInterrupted)
+			}
 			if err != nil {
-				fmt.Printf("%v Waiting for our turn failed (err: %s), retrying in %v...\n", time.Now().Format("2006-01-02 15:04:05"), err, tryContributeAttemptDelay)
-				time.Sleep(tryContributeAttemptDelay)
-				continue
+				log.Fatalf("waiting for our turn: %s", err)
 			}
 			if !ok {
 				fmt.Printf("%v Can't enter the lobby, are you sure we're on your reserved slot? Waiting %v for retrying...\n", time.Now().Format("2006-01-02 15:04:05"), tryContributeAttemptDelay)
-				time.Sleep(tryContributeAttemptDelay)
+				select {
+				case <-time.After(tryContributeAttemptDelay):
+				case <-ctx.Done():
+					log.Fatalf("%s", errInterrupted)
+				}
 				continue
 			}
+			currentState = cb
 			break
 		}
 
-		fmt.Printf("Sending our precomputed contribution %s to the sequencer...\n", args[0])
-		var contributionReceipt *sequencerclient.ContributionReceipt
-		for {
-			var err error
-			contributionReceipt, err = client.Contribute(cmd.Context(), sessionID, contributionBatch)
+		// The sequencer rejects a contribution that doesn't extend the current state, so check it before
+		// sending it to explain why.
+		fmt.Printf("It's our turn! Checking that the contribution extends the current state... ")
+		ok, err = contributionBatch.Verify(currentState)
+		if err != nil || !ok {
+			fmt.Printf("FAILED\n")
+			reason := "the contribution file doesn't extend the current state of the ceremony, most likely it was computed from a stale state (e.g: someone contributed after it was downloaded)"
 			if err != nil {
-				fmt.Printf("Failed sending contribution!: %s\n", err)
-				fmt.Printf("Retrying sending contribution in %v\n", sendContributionRetryDelay)
-				time.Sleep(sendContributionRetryDelay)
-				continue
+				reason = fmt.Sprintf("the contribution file doesn't extend the current state of the ceremony: %s", err)
 			}
-			break
+			if abortIfStale {
+				stop()
+				abortContribution(client, sessionID)
+				log.Fatalf("%s. Download the current state with `kzgcli offline download-state` and compute a new contribution", reason)
+			}
+			statePath := fmt.Sprintf("current_state_%s.json", sessionID)
+			stateJSON, err := contribution.Encode(currentState, true)
+			if err != nil {
+				log.Fatalf("%s. Encoding the current state failed: %s", reason, err)
+			}
+			if err := os.WriteFile(statePath, stateJSON, os.ModePerm); err != nil {
+				log.Fatalf("%s. Saving the current state failed: %s", reason, err)
+			}
+			log.Fatalf("%s. We keep our turn until the sequencer compute deadline: compute a new contribution from the current state saved in %s with `kzgcli offline contribute` and send it again, or use --abort-if-stale to release the turn", reason, statePath)
+		}
+		fmt.Printf("OK\n")
+
+		fmt.Printf("Sending our precomputed contribution %s to the sequencer...\n", args[0])
+		contributionReceipt, err := client.Contribute(ctx, sessionID, contributionBatch)
+		if errors.Is(err, sequencerclient.ErrContributionUnconfirmed) {
+			// Our turn isn't aborted, since the sequencer might be applying our contribution.
+			log.Fatalf("sending contribution: %s. Check if it was included with `kzgcli check-inclusion --contribution %s`, and if it wasn't, send it again", err, args[0])
+		}
+		if err != nil {
+			interrupted := ctx.Err() != nil
+			stop()
+			abortContribution(client, sessionID)
+			if interrupted {
+				log.Fatalf("%s", errInterrupted)
+			}
+			log.Fatalf("sending contribution: %s", err)
 		}
 
-		// Persist the receipt and contribution.
+		// Persist the receipt and contribution. The offline flow isn't journaled, but the files are named as in
+		// the contribute work directory.
+		files := &contributeJournal{dir: ".", SessionID: sessionID}
 		receiptJSON, _ := json.Marshal(contributionReceipt)
-		if err := os.WriteFile(fmt.Sprintf("contribution_receipt_%s.json", sessionID), receiptJSON, os.ModePerm); err != nil {
+		if err := os.WriteFile(files.receiptPath(), receiptJSON, os.ModePerm); err != nil {
 			log.Fatalf("failed to save the contribution receipt (err: %s), printing to stdout as last resort: %s", err, receiptJSON)
 		}
 		ourContributionBatchJSON, _ := contribution.Encode(contributionBatch, true)
-		if err := os.WriteFile(fmt.Sprintf("my_contribution_%s.json", sessionID), ourContributionBatchJSON, os.ModePerm); err != nil {
+		if err := os.WriteFile(files.contributionPath(), ourContributionBatchJSON, os.ModePerm); err != nil {
 			log.Fatalf("failed to save the contribution (err: %s), printing to stdout as last resort: %s", err, ourContributionBatchJSON)
 		}
 
+		checkContributionReceipt(cmd.Context(), client, files, "", contributionReceipt, contributionBatch)
+
 		fmt.Printf("Success!\n")
 	},
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jsign/go-kzg-ceremony-client/eip712"
+	"github.com/jsign/go-kzg-ceremony-client/sequencer"
+	"github.com/spf13/cobra"
+)
+
+var sequencerCmd = &cobra.Command{
+	Use:   "sequencer",
+	Short: "Contains commands to run a local sequencer for private ceremonies and testing",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Usage(); err != nil {
+			log.Fatalf("cmd usage failed: %s", err)
+		}
+	},
+}
+
+var sequencerServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Runs a sequencer serving the same HTTP API as the official one",
+	Run: func(cmd *cobra.Command, args []string) {
+		listenAddr, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			log.Fatalf("get --listen flag value: %s", err)
+		}
+		params, err := getCeremonyParameters(cmd)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		computeDeadline, err := cmd.Flags().GetDuration("compute-deadline")
+		if err != nil {
+			log.Fatalf("get --compute-deadline flag value: %s", err)
+		}
+		participants, err := cmd.Flags().GetStringSlice("participant")
+		if err != nil {
+			log.Fatalf("get --participant flag value: %s", err)
+		}
+		keyPath, err := cmd.Flags().GetString("key-file")
+		if err != nil {
+			log.Fatalf("get --key-file flag value: %s", err)
+		}
+		var key *ecdsa.PrivateKey
+		if keyPath != "" {
+			if key, err = eip712.LoadHexKey(keyPath); err != nil {
+				log.Fatalf("loading sequencer key: %s", err)
+			}
+		}
+
+		seq, err := sequencer.New(sequencer.Config{
+			Parameters:      params,
+			ComputeDeadline: computeDeadline,
+			Key:             key,
+		})
+		if err != nil {
+			log.Fatalf("creating sequencer: %s", err)
+		}
+		fmt.Printf("Sequencer address: %s\n", seq.Address())
+		for _, participant := range participants {
+			sessionID, err := seq.NewSession(participant)
+			if err != nil {
+				log.Fatalf("creating session for %s: %s", participant, err)
+			}
+			fmt.Printf("Session id for %s: %s\n", participant, sessionID)
+		}
+
+		server := &http.Server{
+			Addr:              listenAddr,
+			Handler:           seq.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				log.Printf("shutting down server: %s", err)
+			}
+		}()
+
+		fmt.Printf("Listening on %s...\n", listenAddr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("serving: %s", err)
+		}
+	},
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/jsign/go-kzg-ceremony-client/sequencerclient"
 	"github.com/spf13/cobra"
 )
 
@@ -12,11 +11,7 @@ var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Returns the current status of the sequencer",
 	Run: func(cmd *cobra.Command, args []string) {
-		sequencerURL, err := cmd.Flags().GetString("sequencer-url")
-		if err != nil {
-			log.Fatalf("get --sequencer-url flag value: %s", err)
-		}
-		client, err := sequencerclient.New(sequencerURL)
+		client, err := newSequencerClient(cmd)
 		if err != nil {
 			log.Fatalf("creating sequencer client: %s", err)
 		}
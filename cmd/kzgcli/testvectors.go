@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/spf13/cobra"
+)
+
+var testVectorsCmd = &cobra.Command{
+	Use:   "testvectors",
+	Short: "Contains commands to generate deterministic test vectors to cross-check client implementations",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Usage(); err != nil {
+			log.Fatalf("cmd usage failed: %s", err)
+		}
+	},
+}
+
+type testVectorSecrets struct {
+	Seed    string   `json:"seed"`
+	Secrets []string `json:"secrets"`
+}
+
+var testVectorsGenerateCmd = &cobra.Command{
+	Use:   "generate <output-dir>",
+	Short: "Generates a deterministic contribution from a seed, saving the initial state, secrets and updated state in a folder",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			log.Fatalf("one argument expected")
+		}
+
+		seedHex, err := cmd.Flags().GetString("seed")
+		if err != nil {
+			log.Fatalf("get --seed flag value: %s", err)
+		}
+		seed, err := hex.DecodeString(strings.TrimPrefix(seedHex, "0x"))
+		if err != nil {
+			log.Fatalf("decoding hex seed: %s", err)
+		}
+		if len(seed) == 0 {
+			log.Fatalf("the seed can't be empty")
+		}
+
+		initialPath, err := cmd.Flags().GetString("initial")
+		if err != nil {
+			log.Fatalf("get --initial flag value: %s", err)
+		}
+		var initialBytes []byte
+		if initialPath != "" {
+			if initialBytes, err = os.ReadFile(initialPath); err != nil {
+				log.Fatalf("reading initial state file: %s", err)
+			}
+		} else {
+			params, err := getCeremonyParameters(cmd)
+			if err != nil {
+				log.Fatalf("%s", err)
+			}
+			if initialBytes, err = contribution.Encode(contribution.NewInitialBatchContribution(params), true); err != nil {
+				log.Fatalf("encoding initial state: %s", err)
+			}
+		}
+		bc, err := contribution.DecodeBatchContribution(initialBytes)
+		if err != nil {
+			log.Fatalf("decoding initial state: %s", err)
+		}
+
+		secrets, err := contribution.DeterministicSecrets(seed, len(bc.Contributions))
+		if err != nil {
+			log.Fatalf("deriving secrets: %s", err)
+		}
+		tvSecrets := testVectorSecrets{
+			Seed:    "0x" + hex.EncodeToString(seed),
+			Secrets: make([]string, len(secrets)),
+		}
+		for i := range secrets {
+			secretBytes := secrets[i].Bytes()
+			tvSecrets.Secrets[i] = "0x" + hex.EncodeToString(secretBytes[:])
+		}
+		secretsJSON, err := json.MarshalIndent(tvSecrets, "", "  ")
+		if err != nil {
+			log.Fatalf("encoding secrets: %s", err)
+		}
+
+		fmt.Printf("Calculating deterministic contribution... ")
+		if err := bc.ContributeDeterministic(seed); err != nil {
+			log.Fatalf("calculating contribution: %s", err)
+		}
+		updatedBytes, err := contribution.Encode(bc, true)
+		if err != nil {
+			log.Fatalf("encoding updated state: %s", err)
+		}
+		fmt.Printf("OK\n")
+
+		if err := os.MkdirAll(args[0], os.ModePerm); err != nil {
+			log.Fatalf("creating output folder: %s", err)
+		}
+		files := map[string][]byte{
+			"initialContribution.json": initialBytes,
+			"secrets.json":             secretsJSON,
+			"updatedContribution.json": updatedBytes,
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(args[0], name), content, os.ModePerm); err != nil {
+				log.Fatalf("writing %s: %s", name, err)
+			}
+		}
+
+		fmt.Printf("Saved test vector in %s\n", args[0])
+	},
+}
+
+// getCeremonyParameters returns the ceremony parameters of the --parameters flag.
+func getCeremonyParameters(cmd *cobra.Command) ([]contribution.SubCeremonyParameters, error) {
+	parametersStr, err := cmd.Flags().GetString("parameters")
+	if err != nil {
+		return nil, fmt.Errorf("get --parameters flag value: %s", err)
+	}
+	params, err := parseCeremonyParameters(parametersStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ceremony parameters: %s", err)
+	}
+	return params, nil
+}
+
+// parseCeremonyParameters parses a comma separated list of <numG1Powers>x<numG2Powers> sub-ceremony parameters.
+func parseCeremonyParameters(s string) ([]contribution.SubCeremonyParameters, error) {
+	var params []contribution.SubCeremonyParameters
+	for _, p := range strings.Split(s, ",") {
+		powers := strings.Split(strings.TrimSpace(p), "x")
+		if len(powers) != 2 {
+			return nil, fmt.Errorf("sub-ceremony parameters %s must have the format <numG1Powers>x<numG2Powers>", p)
+		}
+		numG1Powers, err := strconv.Atoi(powers[0])
+		if err != nil {
+			return nil, fmt.Errorf("parsing number of G1 powers: %s", err)
+		}
+		numG2Powers, err := strconv.Atoi(powers[1])
+		if err != nil {
+			return nil, fmt.Errorf("parsing number of G2 powers: %s", err)
+		}
+		if numG1Powers < 2 || numG2Powers < 2 {
+			return nil, fmt.Errorf("at least two G1 and G2 powers are needed")
+		}
+		params = append(params, contribution.SubCeremonyParameters{NumG1Powers: numG1Powers, NumG2Powers: numG2Powers})
+	}
+	return params, nil
+}
+
+func formatCeremonyParameters(params []contribution.SubCeremonyParameters) string {
+	formatted := make([]string, len(params))
+	for i, p := range params {
+		formatted[i] = fmt.Sprintf("%dx%d", p.NumG1Powers, p.NumG2Powers)
+	}
+	return strings.Join(formatted, ",")
+}
@@ -1,39 +1,149 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
 	"time"
 
-	"github.com/jsign/go-kzg-ceremony-client/sequencerclient"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/transcript"
 	"github.com/spf13/cobra"
 )
 
+// maxPrintedFailures is the maximum number of failures printed per check in the table format.
+const maxPrintedFailures = 10
+
 var verifyTranscriptCmd = &cobra.Command{
 	Use:   "verify-transcript",
 	Short: "Pulls and verifies the current sequencer transcript",
 	Run: func(cmd *cobra.Command, args []string) {
-		sequencerURL, err := cmd.Flags().GetString("sequencer-url")
+		exhaustive, err := cmd.Flags().GetBool("exhaustive")
+		if err != nil {
+			log.Fatalf("get --exhaustive flag value: %s", err)
+		}
+		checkBLS, err := cmd.Flags().GetBool("check-bls")
 		if err != nil {
-			log.Fatalf("get --sequencer-url flag value: %s", err)
+			log.Fatalf("get --check-bls flag value: %s", err)
 		}
-		client, err := sequencerclient.New(sequencerURL)
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			log.Fatalf("get --format flag value: %s", err)
+		}
+		if format != "table" && format != "json" {
+			log.Fatalf("unknown format %s, it must be table or json", format)
+		}
+		params, err := getCeremonyParameters(cmd)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		client, err := newSequencerClient(cmd)
 		if err != nil {
 			log.Fatalf("creating sequencer client: %s", err)
 		}
 
-		fmt.Printf("Pulling current transcript from sequencer... ")
-		transcript, err := client.GetCurrentTranscript(cmd.Context())
-		if err != nil {
-			log.Fatalf("get sequencer status: %s", err)
+		// In the JSON format the standard output only contains the report.
+		printf := fmt.Printf
+		if format == "json" {
+			printf = func(string, ...interface{}) (int, error) { return 0, nil }
 		}
-		fmt.Printf("OK\n")
 
-		fmt.Printf("Verifying transcript... ")
+		printf("Pulling and decoding current transcript from sequencer... ")
 		now := time.Now()
-		if err := transcript.Verify(); err != nil {
-			log.Fatalf("verifying transcript: %s", err)
+		batchTranscript, err := client.GetCurrentTranscript(cmd.Context())
+		var decodeErr error
+		if err != nil {
+			var validationErr *contribution.ValidationError
+			if !errors.As(err, &validationErr) {
+				log.Fatalf("get current transcript: %s", err)
+			}
+			decodeErr = validationErr
+		}
+		// The schema and subgroup checks are done while the transcript is streamed.
+		report := &transcript.VerifyReport{Checks: transcript.DecodeCheckResults(batchTranscript, decodeErr, time.Since(now))}
+		if err == nil {
+			printf("OK\n")
+			printf("Verifying transcript... ")
+			verifyReport := batchTranscript.VerifyReport(transcript.VerifyOptions{
+				Parameters:    params,
+				Exhaustive:    exhaustive,
+				BLSSignatures: checkBLS,
+			})
+			report.Checks = append(report.Checks, verifyReport.Checks...)
+			printf("done (took %.02fs)\n", verifyReport.Duration.Seconds())
+		} else {
+			printf("failed\n")
+		}
+		report.Duration = time.Since(now)
+
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				log.Fatalf("encoding report: %s", err)
+			}
+		} else {
+			printReportTable(report)
 		}
-		fmt.Printf("Valid! (took %.02fs)\n", time.Since(now).Seconds())
+
+		if err := report.Err(); err != nil {
+			log.Fatalf("the transcript isn't valid: %s", err)
+		}
+		printf("Valid!\n")
 	},
 }
+
+func printReportTable(report *transcript.VerifyReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "CHECK\tSUB-CEREMONY\tRESULT\tCHECKED\tFAILURES\tDURATION\n")
+	for _, result := range report.Checks {
+		subCeremony := "all"
+		if result.SubCeremony >= 0 {
+			subCeremony = strconv.Itoa(result.SubCeremony)
+		}
+		status := "passed"
+		if !result.Passed {
+			status = "FAILED"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", result.Check, subCeremony, status, result.Checked, len(result.Failures), result.Duration.Round(time.Millisecond))
+	}
+	_ = w.Flush()
+
+	for _, result := range report.Checks {
+		if result.Signatures != nil {
+			fmt.Printf("%s: %d valid, %d invalid, %d absent\n", result.Check, result.Signatures.Valid, result.Signatures.Invalid, result.Signatures.Absent)
+		}
+	}
+
+	for _, result := range report.Checks {
+		if len(result.Failures) == 0 {
+			continue
+		}
+		fmt.Printf("\nFailures of %s", result.Check)
+		if result.SubCeremony >= 0 {
+			fmt.Printf(" in sub-ceremony %d", result.SubCeremony)
+		}
+		fmt.Printf(":\n")
+		for i, failure := range result.Failures {
+			if i == maxPrintedFailures {
+				fmt.Printf("  ... and %d more (use --format json to get all of them)\n", len(result.Failures)-maxPrintedFailures)
+				break
+			}
+			fmt.Printf("  -")
+			if failure.Index >= 0 {
+				fmt.Printf(" #%d", failure.Index)
+			}
+			if failure.Participant != "" {
+				fmt.Printf(" %s", failure.Participant)
+			}
+			if failure.Path != "" {
+				fmt.Printf(" (%s)", failure.Path)
+			}
+			fmt.Printf(": %s\n", failure.Message)
+		}
+	}
+}
@@ -4,41 +4,71 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"runtime"
 
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	bls12381Fr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"golang.org/x/sync/errgroup"
 )
 
 type BatchContribution struct {
 	Contributions []Contribution
+	// ECDSASignature is the (optional) EIP-712 signature of the potPubKeys by an Ethereum participant.
+	ECDSASignature []byte
 }
 
-func (bc *BatchContribution) Contribute(extRandomness ...[]byte) error {
-	frs := make([]*bls12381Fr.Element, len(bc.Contributions))
-	for i := range frs {
-		frs[i] = &bls12381Fr.Element{}
-		if _, err := frs[i].SetRandom(); err != nil {
-			return fmt.Errorf("get random Fr: %s", err)
-		}
+// Contribute updates every sub-ceremony with a fresh secret. If identity isn't empty, each sub-ceremony
+// secret is also used to BLS sign it, binding the contribution to the participant identity.
+// The secrets are derived from the CSRNG and the (optional) external randomness as described in deriveSecrets.
+func (bc *BatchContribution) Contribute(identity string, extRandomness ...[]byte) error {
+	csrng, err := csrngEntropy()
+	if err != nil {
+		return fmt.Errorf("get CSRNG entropy: %s", err)
+	}
+	secrets, err := deriveSecrets(csrng, extRandomness, len(bc.Contributions))
+	// Cleanup in-memory entropy.
+	for i := range csrng {
+		csrng[i] = 0
+	}
+	if err != nil {
+		return fmt.Errorf("deriving secrets: %s", err)
+	}
 
-		// For every externally provided randomness (if any), we multiply frs[i] (generated with CSRNG),
-		// with a proper map from the []bytes->Fr.
-		for _, externalRandomness := range extRandomness {
-			extFr := &bls12381Fr.Element{}
+	frs := make([]*bls12381Fr.Element, len(secrets))
+	for i := range secrets {
+		frs[i] = &secrets[i]
+	}
+
+	return bc.contributeWithFrs(identity, frs)
+}
 
-			// SetBytes() is safe to call with an arbitrary sized []byte since:
-			// - A big.Int `r` will be created from the bytes, which is an arbitrary precision integer.
-			// - gnark-crypto will automatically make r.Mod(BLS_MODULUS) to have a proper Fr.
-			extFr.SetBytes(externalRandomness)
+// ContributeDeterministic updates every sub-ceremony with secrets derived from the seed as described in
+// DeterministicSecrets. This is useful to generate reproducible test vectors, and must never be used to
+// contribute to a real ceremony since anyone knowing the seed knows the secrets.
+func (bc *BatchContribution) ContributeDeterministic(seed []byte) error {
+	secrets, err := DeterministicSecrets(seed, len(bc.Contributions))
+	if err != nil {
+		return fmt.Errorf("deriving secrets: %s", err)
+	}
 
-			frs[i].Mul(frs[i], extFr)
-		}
+	frs := make([]*bls12381Fr.Element, len(secrets))
+	for i := range secrets {
+		frs[i] = &secrets[i]
 	}
 
-	return bc.contributeWithFrs(frs)
+	return bc.contributeWithFrs("", frs)
 }
 
-func (bc *BatchContribution) contributeWithSecrets(secrets []string) error {
+// DeterministicSecrets derives n sub-ceremony secrets from the seed. The derivation is the same as in Contribute,
+// using the seed as the CSRNG output and without external randomness.
+func DeterministicSecrets(seed []byte, n int) ([]bls12381Fr.Element, error) {
+	if len(seed) == 0 {
+		return nil, fmt.Errorf("the seed can't be empty")
+	}
+	return deriveSecrets(seed, nil, n)
+}
+
+func (bc *BatchContribution) contributeWithSecrets(identity string, secrets []string) error {
 	frs := make([]*bls12381Fr.Element, len(secrets))
 	for i := range secrets {
 		secretBytes, err := hex.DecodeString(secrets[i][2:])
@@ -52,10 +82,53 @@ func (bc *BatchContribution) contributeWithSecrets(secrets []string) error {
 		}
 	}
 
-	return bc.contributeWithFrs(frs)
+	return bc.contributeWithFrs(identity, frs)
 }
 
-func (bc *BatchContribution) contributeWithFrs(frs []*bls12381Fr.Element) error {
+func (bc *BatchContribution) contributeWithFrs(identity string, frs []*bls12381Fr.Element) error {
+	// Cleanup in-memory secrets, whether the contribution succeeds or not.
+	defer func() {
+		for i := range frs {
+			*frs[i] = bls12381Fr.Element{}
+		}
+	}()
+
+	var hashedIdentity *bls12381.G1Affine
+	if identity != "" {
+		if err := ValidateIdentity(identity); err != nil {
+			return fmt.Errorf("invalid identity: %s", err)
+		}
+		h, err := HashIdentity(identity)
+		if err != nil {
+			return fmt.Errorf("hashing identity: %s", err)
+		}
+		hashedIdentity = &h
+	}
+
+	if len(frs) != len(bc.Contributions) {
+		return fmt.Errorf("there're %d secrets for %d sub-ceremonies", len(frs), len(bc.Contributions))
+	}
+	for i := range frs {
+		if frs[i].IsZero() {
+			return fmt.Errorf("the %d-th secret is zero", i)
+		}
+	}
+	// The powers are updated in place by index, so inconsistent or too few powers are rejected before.
+	for i := range bc.Contributions {
+		c := &bc.Contributions[i]
+		params := SubCeremonyParameters{NumG1Powers: c.NumG1Powers, NumG2Powers: c.NumG2Powers}
+		if err := c.parameterCheck(IndexPath("contributions", i), params); err != nil {
+			return fmt.Errorf("invalid sub-ceremony: %w", err)
+		}
+	}
+
+	// The sub-ceremonies are contributed in parallel, so the cores are split between them proportionally to their
+	// number of powers instead of each one using all of them.
+	var totalG1Powers int
+	for i := range bc.Contributions {
+		totalG1Powers += bc.Contributions[i].NumG1Powers
+	}
+
 	var g errgroup.Group
 
 	for i := range bc.Contributions {
@@ -64,12 +137,18 @@ func (bc *BatchContribution) contributeWithFrs(frs []*bls12381Fr.Element) error
 				xBig := big.NewInt(0)
 				frs[i].BigInt(xBig)
 
-				contribution.updatePowersOfTau(xBig)
+				numChunks := 1
+				if totalG1Powers > 0 {
+					numChunks = runtime.NumCPU() * contribution.NumG1Powers / totalG1Powers
+				}
+				contribution.updatePowersOfTau(frs[i], numChunks)
 				contribution.updateWitness(xBig)
+				if hashedIdentity != nil {
+					contribution.updateBLSSignature(xBig, hashedIdentity)
+				}
 
 				// Cleanup in-memory secret.
 				xBig.SetInt64(0)
-				*frs[i] = bls12381Fr.Element{}
 
 				return nil
 			}
@@ -79,10 +158,48 @@ func (bc *BatchContribution) contributeWithFrs(frs []*bls12381Fr.Element) error
 		return fmt.Errorf("contributing to sub-ceremony: %s", err)
 	}
 
+	// Any previous ECDSA signature was for the replaced potPubKeys.
+	bc.ECDSASignature = nil
+
 	return nil
 }
 
+// PowersCheck checks that the powers of every sub-ceremony are consistent powers of the same tau, with the
+// generators as first powers. Unlike Verify, it doesn't need the previous batch contribution, so a contribution
+// that passes this check but not Verify is well formed but doesn't extend the previous one (e.g: it was computed
+// from a stale state).
+func (bc *BatchContribution) PowersCheck() (bool, error) {
+	for i := range bc.Contributions {
+		c := &bc.Contributions[i]
+		if len(c.PowersOfTau.G1Affines) < 2 || len(c.PowersOfTau.G2Affines) < 2 {
+			return false, fmt.Errorf("the %d-th contribution has less than two G1 or G2 powers", i)
+		}
+		if !c.PowersOfTau.G1Affines[0].Equal(&g1Generator) || !c.PowersOfTau.G2Affines[0].Equal(&g2Generator) {
+			return false, nil
+		}
+		ok, err := c.powersCheck()
+		if err != nil {
+			return false, fmt.Errorf("checking %d-th contribution powers: %s", i, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Verify checks that every sub-ceremony contribution is a valid update of the previous batch contribution.
+//
+// The spec `schema_check` and `subgroup_checks` are done when decoding, so here the `parameter_check` uses the
+// previous batch contribution as the source of the ceremony parameters.
 func (bc *BatchContribution) Verify(prevBatchContribution *BatchContribution) (bool, error) {
+	params := prevBatchContribution.Parameters()
+	if err := prevBatchContribution.ParameterCheck(params); err != nil {
+		return false, fmt.Errorf("parameter check of previous batch contribution: %s", err)
+	}
+	if err := bc.ParameterCheck(params); err != nil {
+		return false, fmt.Errorf("parameter check: %s", err)
+	}
 	for i, contribution := range prevBatchContribution.Contributions {
 		ok, err := bc.Contributions[i].Verify(&contribution)
 		if err != nil {
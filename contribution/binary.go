@@ -0,0 +1,233 @@
+package contribution
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"runtime"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"golang.org/x/sync/errgroup"
+)
+
+// The binary container stores a batch contribution as:
+//
+//	magic "KZGC" | version (1 byte) | flags (1 byte) | numContributions (uint32)
+//	numContributions * [numG1Powers (uint32) | numG2Powers (uint32) | contribution flags (1 byte)]
+//	numContributions * [G1 powers | G2 powers | potPubKey | BLS signature (if flagged)]
+//	ECDSA signature (65 bytes, if flagged)
+//	SHA-256 checksum of all the previous bytes
+//
+// Integers are big-endian, and points are stored back to back compressed (or uncompressed if flagged) in the
+// same serialization as the JSON format.
+const (
+	binaryMagic   = "KZGC"
+	binaryVersion = 1
+
+	binaryFlagUncompressed   = 1 << 0
+	binaryFlagECDSASignature = 1 << 1
+
+	binaryContributionFlagBLSSignature = 1 << 0
+
+	binaryHeaderSize             = len(binaryMagic) + 1 + 1 + 4
+	binaryContributionHeaderSize = 4 + 4 + 1
+	ecdsaSignatureSize           = 65
+)
+
+// binaryPointsChunkSize is the number of points decoded by each goroutine.
+const binaryPointsChunkSize = 1024
+
+// IsBinary returns true if the bytes look like a binary container instead of JSON.
+func IsBinary(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(binaryMagic))
+}
+
+// EncodeBinary encodes the batch contribution in the binary container format. If uncompressed is true, points
+// are stored uncompressed which doubles the size but saves the decompression work when decoding.
+func EncodeBinary(bc *BatchContribution, uncompressed bool) ([]byte, error) {
+	if len(bc.ECDSASignature) != 0 && len(bc.ECDSASignature) != ecdsaSignatureSize {
+		return nil, fmt.Errorf("the ECDSA signature has %d bytes but %d were expected", len(bc.ECDSASignature), ecdsaSignatureSize)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(binaryMagic)
+	buf.WriteByte(binaryVersion)
+	var flags byte
+	if uncompressed {
+		flags |= binaryFlagUncompressed
+	}
+	if len(bc.ECDSASignature) != 0 {
+		flags |= binaryFlagECDSASignature
+	}
+	buf.WriteByte(flags)
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(bc.Contributions)))
+	for _, c := range bc.Contributions {
+		if c.NumG1Powers != len(c.PowersOfTau.G1Affines) || c.NumG2Powers != len(c.PowersOfTau.G2Affines) {
+			return nil, fmt.Errorf("the number of powers doesn't match the declared %d G1 and %d G2 powers", c.NumG1Powers, c.NumG2Powers)
+		}
+		_ = binary.Write(&buf, binary.BigEndian, uint32(c.NumG1Powers))
+		_ = binary.Write(&buf, binary.BigEndian, uint32(c.NumG2Powers))
+		var contributionFlags byte
+		if c.BLSSignature != nil {
+			contributionFlags |= binaryContributionFlagBLSSignature
+		}
+		buf.WriteByte(contributionFlags)
+	}
+
+	writeG1 := func(p *bls12381.G1Affine) {
+		if uncompressed {
+			pBytes := p.RawBytes()
+			buf.Write(pBytes[:])
+			return
+		}
+		pBytes := p.Bytes()
+		buf.Write(pBytes[:])
+	}
+	writeG2 := func(p *bls12381.G2Affine) {
+		if uncompressed {
+			pBytes := p.RawBytes()
+			buf.Write(pBytes[:])
+			return
+		}
+		pBytes := p.Bytes()
+		buf.Write(pBytes[:])
+	}
+	for i := range bc.Contributions {
+		c := &bc.Contributions[i]
+		for j := range c.PowersOfTau.G1Affines {
+			writeG1(&c.PowersOfTau.G1Affines[j])
+		}
+		for j := range c.PowersOfTau.G2Affines {
+			writeG2(&c.PowersOfTau.G2Affines[j])
+		}
+		writeG2(&c.PotPubKey)
+		if c.BLSSignature != nil {
+			writeG1(c.BLSSignature)
+		}
+	}
+	buf.Write(bc.ECDSASignature)
+
+	checksum := sha256.Sum256(buf.Bytes())
+	buf.Write(checksum[:])
+
+	return buf.Bytes(), nil
+}
+
+// DecodeBinaryBatchContribution decodes a batch contribution from the binary container format. As in the JSON
+// format, all the points are subgroup checked.
+func DecodeBinaryBatchContribution(b []byte) (*BatchContribution, error) {
+	if !IsBinary(b) {
+		return nil, fmt.Errorf("missing binary container magic bytes")
+	}
+	if len(b) < binaryHeaderSize+sha256.Size {
+		return nil, fmt.Errorf("the binary container is too short (%d bytes)", len(b))
+	}
+	content, checksum := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+	if expected := sha256.Sum256(content); !bytes.Equal(checksum, expected[:]) {
+		return nil, fmt.Errorf("checksum mismatch, the file is corrupted")
+	}
+
+	version, flags := content[len(binaryMagic)], content[len(binaryMagic)+1]
+	if version != binaryVersion {
+		return nil, fmt.Errorf("unsupported binary container version %d", version)
+	}
+	if flags&^(binaryFlagUncompressed|binaryFlagECDSASignature) != 0 {
+		return nil, fmt.Errorf("unknown flags %08b", flags)
+	}
+	g1Size, g2Size := bls12381.SizeOfG1AffineCompressed, bls12381.SizeOfG2AffineCompressed
+	if flags&binaryFlagUncompressed != 0 {
+		g1Size, g2Size = bls12381.SizeOfG1AffineUncompressed, bls12381.SizeOfG2AffineUncompressed
+	}
+
+	numContributions := uint64(binary.BigEndian.Uint32(content[len(binaryMagic)+2:]))
+	offset := uint64(binaryHeaderSize)
+	if uint64(len(content)) < offset+numContributions*uint64(binaryContributionHeaderSize) {
+		return nil, fmt.Errorf("the binary container is too short for %d contributions", numContributions)
+	}
+
+	// Check the sizes in the header match the content before allocating anything.
+	bc := BatchContribution{Contributions: make([]Contribution, numContributions)}
+	bodySize := uint64(0)
+	for i := range bc.Contributions {
+		numG1Powers := uint64(binary.BigEndian.Uint32(content[offset:]))
+		numG2Powers := uint64(binary.BigEndian.Uint32(content[offset+4:]))
+		contributionFlags := content[offset+8]
+		offset += uint64(binaryContributionHeaderSize)
+		if contributionFlags&^binaryContributionFlagBLSSignature != 0 {
+			return nil, fmt.Errorf("unknown flags %08b in %d-th contribution", contributionFlags, i)
+		}
+
+		bc.Contributions[i].NumG1Powers = int(numG1Powers)
+		bc.Contributions[i].NumG2Powers = int(numG2Powers)
+		bodySize += numG1Powers*uint64(g1Size) + (numG2Powers+1)*uint64(g2Size)
+		if contributionFlags&binaryContributionFlagBLSSignature != 0 {
+			bc.Contributions[i].BLSSignature = &bls12381.G1Affine{}
+			bodySize += uint64(g1Size)
+		}
+	}
+	if flags&binaryFlagECDSASignature != 0 {
+		bodySize += ecdsaSignatureSize
+	}
+	if uint64(len(content)) != offset+bodySize {
+		return nil, fmt.Errorf("the binary container has %d bytes but the header describes %d", len(content), offset+bodySize)
+	}
+
+	var group errgroup.Group
+	group.SetLimit(runtime.NumCPU())
+	for i := range bc.Contributions {
+		i, c := i, &bc.Contributions[i]
+		c.PowersOfTau.G1Affines = make([]bls12381.G1Affine, c.NumG1Powers)
+		c.PowersOfTau.G2Affines = make([]bls12381.G2Affine, c.NumG2Powers)
+
+		g1Powers := content[offset : offset+uint64(c.NumG1Powers*g1Size)]
+		offset += uint64(len(g1Powers))
+		for start := 0; start < c.NumG1Powers; start += binaryPointsChunkSize {
+			start := start
+			group.Go(func() error {
+				for k := start; k < start+binaryPointsChunkSize && k < c.NumG1Powers; k++ {
+					path := IndexPath(JoinPath(IndexPath("contributions", i), "powersOfTau.G1Powers"), k)
+					if err := DecodeG1Point(path, g1Powers[k*g1Size:(k+1)*g1Size], &c.PowersOfTau.G1Affines[k]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+
+		g2Powers := content[offset : offset+uint64(c.NumG2Powers*g2Size)]
+		offset += uint64(len(g2Powers))
+		potPubKey := content[offset : offset+uint64(g2Size)]
+		offset += uint64(g2Size)
+		var blsSignature []byte
+		if c.BLSSignature != nil {
+			blsSignature = content[offset : offset+uint64(g1Size)]
+			offset += uint64(g1Size)
+		}
+		group.Go(func() error {
+			path := IndexPath("contributions", i)
+			for k := range c.PowersOfTau.G2Affines {
+				if err := DecodeG2Point(IndexPath(JoinPath(path, "powersOfTau.G2Powers"), k), g2Powers[k*g2Size:(k+1)*g2Size], &c.PowersOfTau.G2Affines[k]); err != nil {
+					return err
+				}
+			}
+			if err := DecodeG2Point(JoinPath(path, "potPubkey"), potPubKey, &c.PotPubKey); err != nil {
+				return err
+			}
+			if blsSignature != nil {
+				if err := DecodeG1Point(JoinPath(path, "blsSignature"), blsSignature, c.BLSSignature); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if flags&binaryFlagECDSASignature != 0 {
+		bc.ECDSASignature = append([]byte(nil), content[offset:offset+ecdsaSignatureSize]...)
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	return &bc, nil
+}
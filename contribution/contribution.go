@@ -3,15 +3,19 @@ package contribution
 import (
 	"fmt"
 	"math/big"
+	"sync"
 
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	bls12381Fr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 )
 
-var g2Generator bls12381.G2Affine
+var (
+	g1Generator bls12381.G1Affine
+	g2Generator bls12381.G2Affine
+)
 
 func init() {
-	_, _, _, g2Generator = bls12381.Generators()
+	_, _, g1Generator, g2Generator = bls12381.Generators()
 }
 
 type PowersOfTau struct {
@@ -24,46 +28,142 @@ type Contribution struct {
 	NumG2Powers int
 	PowersOfTau PowersOfTau
 	PotPubKey   bls12381.G2Affine
+	// BLSSignature is the (optional) signature of the participant identity with the sub-ceremony secret.
+	BLSSignature *bls12381.G1Affine
 }
 
+// Verify checks that the contribution is a valid update of the previous contribution, running all the checks
+// defined in the spec. It returns an error if the contribution is malformed, and `false` if any of the
+// cryptographic checks fails.
 func (c *Contribution) Verify(previousContribution *Contribution) (bool, error) {
-	//  Check that the updated G1Affine[1] complies with `PotPubKey`.
+	// 1. `parameter_check`: the number of powers must match the previous contribution and the declared lengths.
+	if err := c.checkParameters(previousContribution); err != nil {
+		return false, fmt.Errorf("parameter check: %s", err)
+	}
+
+	// 2. `subgroup_checks` were done when decoding the points with DecodeG1Point and DecodeG2Point, since gnark-crypto
+	//    does the check when decoding G(1|2) bytes. The `schema_check` is also done at decoding time, see SchemaCheck.
+
+	// 3. `non_zero_check`: the PotPubKey can't be the identity, since that would mean a zero secret.
+	if c.PotPubKey.IsInfinity() {
+		return false, nil
+	}
+
+	// 4. The first powers must be the generators, independently of the secret.
+	if !c.PowersOfTau.G1Affines[0].Equal(&g1Generator) || !c.PowersOfTau.G2Affines[0].Equal(&g2Generator) {
+		return false, nil
+	}
+
+	// 5. `tau_update_check`: check that the updated G1Affine[1] complies with `PotPubKey`.
 	//  That is,  newG1Affine[1] = x*oldG1Affine[1].
 	//  We do this with the pairing check `e(oldG1Affine[1], PotPubKey) =?= e(newG1Affine[1], g2)`
 	prevG1Power := previousContribution.PowersOfTau.G1Affines[1]
-	left, err := bls12381.Pair([]bls12381.G1Affine{prevG1Power}, []bls12381.G2Affine{c.PotPubKey})
-	if err != nil {
-		return false, fmt.Errorf("pairing prevG1Power with PotPubKey: %s", err)
-	}
-
-	postG1Power := c.PowersOfTau.G1Affines[1]
-	right, err := bls12381.Pair([]bls12381.G1Affine{postG1Power}, []bls12381.G2Affine{g2Generator})
+	var negPostG1Power bls12381.G1Affine
+	negPostG1Power.Neg(&c.PowersOfTau.G1Affines[1])
+	ok, err := bls12381.PairingCheck(
+		[]bls12381.G1Affine{prevG1Power, negPostG1Power},
+		[]bls12381.G2Affine{c.PotPubKey, g2Generator})
 	if err != nil {
-		return false, fmt.Errorf("pairing postG1Power with G2: %s", err)
+		return false, fmt.Errorf("pairing check of tau update: %s", err)
 	}
-
 	// If the pairing doesn't match, return `false`.
-	if !left.Equal(&right) {
+	if !ok {
 		return false, nil
 	}
 
+	// 6. `g1_powers_check` and `g2_powers_check`: all the powers must be consistent powers of the same tau.
+	ok, err = c.powersCheck()
+	if err != nil || !ok {
+		return false, err
+	}
+
 	// All validations are good, return `true`.
 	return true, nil
 }
 
-func (c *Contribution) updatePowersOfTau(x *big.Int) {
-	xi := big.NewInt(1)
+// powersCheck runs the `g1_powers_check` and `g2_powers_check` of the spec, which don't depend on the previous
+// contribution.
+func (c *Contribution) powersCheck() (bool, error) {
+	g1Pairs, g2Pairs, err := c.PowersOfTau.PowersCheckPairs()
+	if err != nil {
+		return false, fmt.Errorf("folding powers checks: %s", err)
+	}
+	ok, err := bls12381.PairingCheck(g1Pairs, g2Pairs)
+	if err != nil {
+		return false, fmt.Errorf("pairing check of powers: %s", err)
+	}
+	return ok, nil
+}
 
-	for i := 0; i < c.NumG1Powers; i++ {
-		c.PowersOfTau.G1Affines[i].ScalarMultiplication(&c.PowersOfTau.G1Affines[i], xi)
+func (c *Contribution) checkParameters(previousContribution *Contribution) error {
+	params := SubCeremonyParameters{NumG1Powers: previousContribution.NumG1Powers, NumG2Powers: previousContribution.NumG2Powers}
+	if err := previousContribution.parameterCheck("", params); err != nil {
+		return fmt.Errorf("previous contribution: %s", err)
+	}
+	return c.parameterCheck("", params)
+}
+
+// updatePowersOfTau multiplies the i-th powers by x^i. The scalar powers are precomputed in Fr, and the
+// G1 powers are split in numChunks chunks calculated in parallel in Jacobian coordinates, with a single batch
+// conversion back to affine per chunk. The G2 powers are spread across the same chunks.
+func (c *Contribution) updatePowersOfTau(x *bls12381Fr.Element, numChunks int) {
+	xPowers := make([]bls12381Fr.Element, c.NumG1Powers)
+	xPowers[0].SetOne()
+	for i := 1; i < len(xPowers); i++ {
+		xPowers[i].Mul(&xPowers[i-1], x)
+	}
 
-		if i < c.NumG2Powers {
-			c.PowersOfTau.G2Affines[i].ScalarMultiplication(&c.PowersOfTau.G2Affines[i], xi)
+	if numChunks < 1 {
+		numChunks = 1
+	}
+	chunkSize := (c.NumG1Powers + numChunks - 1) / numChunks
+	numChunks = (c.NumG1Powers + chunkSize - 1) / chunkSize
+	var wg sync.WaitGroup
+	for chunk := 0; chunk < numChunks; chunk++ {
+		start, end := chunk*chunkSize, (chunk+1)*chunkSize
+		if end > c.NumG1Powers {
+			end = c.NumG1Powers
 		}
-		xi.Mul(xi, x).Mod(xi, bls12381Fr.Modulus())
+		wg.Add(1)
+		go func(chunk, start, end int) {
+			defer wg.Done()
+
+			var xi big.Int
+			g1Jacs := make([]bls12381.G1Jac, end-start)
+			for i := start; i < end; i++ {
+				xPowers[i].BigInt(&xi)
+				g1Jacs[i-start].ScalarMultiplicationAffine(&c.PowersOfTau.G1Affines[i], &xi)
+			}
+			copy(c.PowersOfTau.G1Affines[start:end], bls12381.BatchJacobianToAffineG1(g1Jacs))
+
+			// The G2 powers are the first ones, so they're interleaved between chunks instead of all landing in
+			// the first one.
+			for i := chunk; i < c.NumG2Powers && i < c.NumG1Powers; i += numChunks {
+				xPowers[i].BigInt(&xi)
+				var g2Jac bls12381.G2Jac
+				g2Jac.FromAffine(&c.PowersOfTau.G2Affines[i])
+				g2Jac.ScalarMultiplication(&g2Jac, &xi)
+				c.PowersOfTau.G2Affines[i].FromJacobian(&g2Jac)
+			}
+
+			// Cleanup in-memory secret powers.
+			xi.SetInt64(0)
+		}(chunk, start, end)
+	}
+	wg.Wait()
+
+	// Cleanup in-memory secret powers.
+	for i := range xPowers {
+		xPowers[i].SetZero()
 	}
 }
 
 func (c *Contribution) updateWitness(x *big.Int) {
 	c.PotPubKey.ScalarMultiplication(&g2Generator, x)
 }
+
+func (c *Contribution) updateBLSSignature(x *big.Int, hashedIdentity *bls12381.G1Affine) {
+	var signature bls12381.G1Affine
+	signature.ScalarMultiplication(hashedIdentity, x)
+	c.BLSSignature = &signature
+}
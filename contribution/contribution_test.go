@@ -2,12 +2,21 @@ package contribution
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	bls12381Fr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"github.com/jsign/go-kzg-ceremony-client/extrand"
 	"github.com/stretchr/testify/require"
 )
@@ -21,7 +30,7 @@ func TestWithInitialContributionFile(t *testing.T) {
 	bc, err := DecodeBatchContribution(contributionFile)
 	require.NoError(t, err)
 
-	err = bc.Contribute()
+	err = bc.Contribute("")
 	require.NoError(t, err)
 
 	originalbc, err := DecodeBatchContribution(contributionFile)
@@ -31,9 +40,104 @@ func TestWithInitialContributionFile(t *testing.T) {
 	require.True(t, ok)
 }
 
+// referenceTestVectorPath is the updated contribution of the reference implementation test-vector, downloaded
+// from referenceTestVectorURL, so the reference implementation is cross-checked without network access.
+const (
+	referenceTestVectorPath = "testdata/reference/updatedContribution.json"
+	referenceTestVectorURL  = "https://raw.githubusercontent.com/jsign/kzg-ceremony-test-vectors/main/updatedContribution.json"
+)
+
 func TestReferenceImplementationTestVector(t *testing.T) {
 	t.Parallel()
 
+	expectedBatchContributionJSON, err := os.ReadFile(referenceTestVectorPath)
+	require.NoError(t, err, "download it with `make reference-test-vector`")
+	testReferenceImplementationTestVector(t, expectedBatchContributionJSON)
+}
+
+func TestGoldenVectors(t *testing.T) {
+	t.Parallel()
+
+	dirs, err := os.ReadDir("testdata/vectors")
+	require.NoError(t, err)
+	require.NotEmpty(t, dirs)
+
+	for _, dir := range dirs {
+		dir := dir
+		t.Run(dir.Name(), func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join("testdata/vectors", dir.Name())
+			initialJSON, err := os.ReadFile(filepath.Join(path, "initialContribution.json"))
+			require.NoError(t, err)
+			updatedJSON, err := os.ReadFile(filepath.Join(path, "updatedContribution.json"))
+			require.NoError(t, err)
+			secretsJSON, err := os.ReadFile(filepath.Join(path, "secrets.json"))
+			require.NoError(t, err)
+			var vectorSecrets struct {
+				Seed    string   `json:"seed"`
+				Secrets []string `json:"secrets"`
+			}
+			require.NoError(t, json.Unmarshal(secretsJSON, &vectorSecrets))
+			seed, err := hex.DecodeString(vectorSecrets.Seed[2:])
+			require.NoError(t, err)
+
+			bc, err := DecodeBatchContribution(initialJSON)
+			require.NoError(t, err)
+
+			secrets, err := DeterministicSecrets(seed, len(bc.Contributions))
+			require.NoError(t, err)
+			require.Len(t, secrets, len(vectorSecrets.Secrets))
+			for i := range secrets {
+				secretBytes := secrets[i].Bytes()
+				require.Equal(t, vectorSecrets.Secrets[i], "0x"+hex.EncodeToString(secretBytes[:]))
+			}
+
+			// Contributing with the explicit secrets or the seed must produce the same (golden) output.
+			require.NoError(t, bc.ContributeDeterministic(seed))
+			gotJSON, err := Encode(bc, true)
+			require.NoError(t, err)
+			require.Equal(t, string(updatedJSON), string(gotJSON))
+
+			bc, err = DecodeBatchContribution(initialJSON)
+			require.NoError(t, err)
+			require.NoError(t, bc.contributeWithSecrets("", vectorSecrets.Secrets))
+			gotJSON, err = Encode(bc, true)
+			require.NoError(t, err)
+			require.Equal(t, string(updatedJSON), string(gotJSON))
+
+			initialBc, err := DecodeBatchContribution(initialJSON)
+			require.NoError(t, err)
+			ok, err := bc.Verify(initialBc)
+			require.NoError(t, err)
+			require.True(t, ok)
+		})
+	}
+}
+
+// TestReferenceImplementationTestVectorOnline is TestReferenceImplementationTestVector with the test-vector
+// pulled from GitHub, which also checks that the committed copy is up to date.
+func TestReferenceImplementationTestVectorOnline(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.Skip("skipping test that pulls the test-vector from GitHub in short mode")
+	}
+
+	res, err := http.DefaultClient.Get(referenceTestVectorURL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	expectedBatchContributionJSON, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	testReferenceImplementationTestVector(t, expectedBatchContributionJSON)
+	if committed, err := os.ReadFile(referenceTestVectorPath); err == nil {
+		require.Equal(t, string(expectedBatchContributionJSON), string(committed), "the committed test-vector is outdated")
+	}
+}
+
+// testReferenceImplementationTestVector compares our contribution with the test-vector generated with the
+// reference implementation.
+func testReferenceImplementationTestVector(t *testing.T, expectedBatchContributionJSON []byte) {
 	subTests := []struct {
 		name     string
 		secrets  []string
@@ -54,35 +158,20 @@ func TestReferenceImplementationTestVector(t *testing.T) {
 		},
 	}
 
+	// Parse the expected batch contribution test-vector.
+	expectedBc, err := DecodeBatchContribution(expectedBatchContributionJSON)
+	require.NoError(t, err)
+
 	for _, subTest := range subTests {
 		subTest := subTest
 		t.Run(subTest.name, func(t *testing.T) {
 			t.Parallel()
 
 			// Start from the initialContribution.json defined in the spec.
-			contributionFile, err := os.ReadFile("testdata/initialContribution.json")
-			require.NoError(t, err)
-
-			// Parse it.
-			bc, err := DecodeBatchContribution(contributionFile)
-			require.NoError(t, err)
+			bc := NewInitialBatchContribution(CeremonyParameters)
 
 			// Calculate the new batch contribution using **our** client logic.
-			err = bc.contributeWithSecrets(subTest.secrets)
-			require.NoError(t, err)
-
-			// In `bc` we have **our** calculated BatchContribution for the defined secrets.
-			// Now we have to compare it against the test-vector result.
-
-			// Pull the expected batch contribution test-vector.
-			res, err := http.DefaultClient.Get("https://raw.githubusercontent.com/jsign/kzg-ceremony-test-vectors/main/updatedContribution.json")
-			require.NoError(t, err)
-			defer res.Body.Close()
-			expectedBatchContributionJSON, err := io.ReadAll(res.Body)
-			require.NoError(t, err)
-
-			// Parse it.
-			expectedBc, err := DecodeBatchContribution(expectedBatchContributionJSON)
+			err := bc.contributeWithSecrets("", subTest.secrets)
 			require.NoError(t, err)
 
 			// Now we'll compare `bc` (our batch contribution) with `expectedBc` (test-vector result).
@@ -156,10 +245,626 @@ func TestExternalRandomness(t *testing.T) {
 	require.NoError(t, err)
 
 	// Contribute with the two available external randomness bytes.
-	err = bc.Contribute(drandres, urlrandmness)
+	err = bc.Contribute("", drandres, urlrandmness)
 	require.NoError(t, err)
 }
 
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	subTests := []struct {
+		name      string
+		tamper    func(bc *BatchContribution)
+		wantOK    bool
+		wantError bool
+	}{
+		{
+			name:   "valid",
+			tamper: func(bc *BatchContribution) {},
+			wantOK: true,
+		},
+		{
+			name: "inconsistent g1 power",
+			tamper: func(bc *BatchContribution) {
+				bc.Contributions[2].PowersOfTau.G1Affines[17] = bc.Contributions[2].PowersOfTau.G1Affines[16]
+			},
+		},
+		{
+			name: "inconsistent g2 power",
+			tamper: func(bc *BatchContribution) {
+				bc.Contributions[1].PowersOfTau.G2Affines[3] = bc.Contributions[1].PowersOfTau.G2Affines[2]
+			},
+		},
+		{
+			name: "first g1 power isn't the generator",
+			tamper: func(bc *BatchContribution) {
+				bc.Contributions[0].PowersOfTau.G1Affines[0] = bc.Contributions[0].PowersOfTau.G1Affines[1]
+			},
+		},
+		{
+			name: "identity potPubKey",
+			tamper: func(bc *BatchContribution) {
+				bc.Contributions[3].PotPubKey = bls12381.G2Affine{}
+			},
+		},
+		{
+			name: "potPubKey of other sub-ceremony",
+			tamper: func(bc *BatchContribution) {
+				bc.Contributions[3].PotPubKey = bc.Contributions[2].PotPubKey
+			},
+		},
+		{
+			name: "missing g1 power",
+			tamper: func(bc *BatchContribution) {
+				bc.Contributions[0].PowersOfTau.G1Affines = bc.Contributions[0].PowersOfTau.G1Affines[1:]
+			},
+			wantError: true,
+		},
+		{
+			name: "wrong number of powers",
+			tamper: func(bc *BatchContribution) {
+				bc.Contributions[0].NumG2Powers++
+			},
+			wantError: true,
+		},
+		{
+			name: "missing sub-ceremony",
+			tamper: func(bc *BatchContribution) {
+				bc.Contributions = bc.Contributions[1:]
+			},
+			wantError: true,
+		},
+	}
+
+	for _, subTest := range subTests {
+		subTest := subTest
+		t.Run(subTest.name, func(t *testing.T) {
+			t.Parallel()
+
+			bc := newTestBatchContribution()
+			require.NoError(t, bc.Contribute(""))
+			subTest.tamper(bc)
+
+			ok, err := bc.Verify(newTestBatchContribution())
+			if subTest.wantError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, subTest.wantOK, ok)
+		})
+	}
+}
+
+func TestPowersCheck(t *testing.T) {
+	t.Parallel()
+
+	state := newTestBatchContribution()
+	require.NoError(t, state.Contribute(""))
+	bc := newTestBatchContribution()
+	require.NoError(t, bc.Contribute(""))
+
+	// A contribution computed from a stale state has consistent powers, but doesn't extend the current state.
+	ok, err := bc.PowersCheck()
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = bc.Verify(state)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	bc.Contributions[2].PowersOfTau.G1Affines[17] = bc.Contributions[2].PowersOfTau.G1Affines[16]
+	ok, err = bc.PowersCheck()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	bc = newTestBatchContribution()
+	require.NoError(t, bc.Contribute(""))
+	bc.Contributions[0].PowersOfTau.G2Affines[0] = bc.Contributions[0].PowersOfTau.G2Affines[1]
+	ok, err = bc.PowersCheck()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	bc = newTestBatchContribution()
+	bc.Contributions[1].PowersOfTau.G1Affines = bc.Contributions[1].PowersOfTau.G1Affines[:1]
+	_, err = bc.PowersCheck()
+	require.Error(t, err)
+}
+
+func TestUpdatePowersOfTau(t *testing.T) {
+	t.Parallel()
+
+	// Start from a contribution with non-trivial powers, so each power is different.
+	bc := newTestBatchContribution()
+	require.NoError(t, bc.Contribute(""))
+	c := bc.Contributions[3]
+	prevG1s := append([]bls12381.G1Affine(nil), c.PowersOfTau.G1Affines...)
+	prevG2s := append([]bls12381.G2Affine(nil), c.PowersOfTau.G2Affines...)
+
+	var x bls12381Fr.Element
+	_, err := x.SetRandom()
+	require.NoError(t, err)
+	var xBig big.Int
+	x.BigInt(&xBig)
+	// Use a few chunks so the G2 powers are spread across them, whatever the number of cores.
+	c.updatePowersOfTau(&x, 3)
+
+	// Compare against a naive affine implementation.
+	xi := big.NewInt(1)
+	for i := range prevG1s {
+		var expected bls12381.G1Affine
+		expected.ScalarMultiplication(&prevG1s[i], xi)
+		require.True(t, expected.Equal(&c.PowersOfTau.G1Affines[i]))
+		if i < len(prevG2s) {
+			var expected bls12381.G2Affine
+			expected.ScalarMultiplication(&prevG2s[i], xi)
+			require.True(t, expected.Equal(&c.PowersOfTau.G2Affines[i]))
+		}
+		xi.Mul(xi, &xBig).Mod(xi, bls12381Fr.Modulus())
+	}
+}
+
+func TestContributeInvalidPowers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		modify func(bc *BatchContribution)
+	}{
+		{name: "empty", modify: func(bc *BatchContribution) { bc.Contributions[1] = Contribution{} }},
+		{name: "more declared G1 powers", modify: func(bc *BatchContribution) { bc.Contributions[1].NumG1Powers++ }},
+		{name: "more declared G2 powers", modify: func(bc *BatchContribution) { bc.Contributions[1].NumG2Powers++ }},
+		{name: "one power", modify: func(bc *BatchContribution) {
+			c := &bc.Contributions[1]
+			c.NumG1Powers, c.NumG2Powers = 1, 1
+			c.PowersOfTau.G1Affines, c.PowersOfTau.G2Affines = c.PowersOfTau.G1Affines[:1], c.PowersOfTau.G2Affines[:1]
+		}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			bc := newTestBatchContribution()
+			test.modify(bc)
+			var validationErr *ValidationError
+			require.ErrorAs(t, bc.Contribute(""), &validationErr)
+			require.ErrorAs(t, bc.ContributeDeterministic([]byte("seed")), &validationErr)
+		})
+	}
+}
+
+func TestDeriveSecrets(t *testing.T) {
+	t.Parallel()
+
+	// The expected values were generated with an independent implementation of RFC 9380 `hash_to_field`.
+	csrng := make([]byte, 64)
+	for i := range csrng {
+		csrng[i] = byte(i)
+	}
+	drandRandomness, err := hex.DecodeString(strings.Repeat("a1", 32))
+	require.NoError(t, err)
+
+	subTests := []struct {
+		name          string
+		extRandomness [][]byte
+		expected      []string
+	}{
+		{
+			name: "only csrng",
+			expected: []string{
+				"0x60772114b518b20ab7fe51bf22464900a0f2de15ed4107338c3a1707b59fbcd6",
+				"0x1e1b759f2e84cdaf0850f911b69fdbb5c8735d88a1a22d968d91821a7dac2a5d",
+				"0x50277a47b352c3927d075fe6c7b2cfd813fdf912b2006243aaa3790be07d1116",
+				"0x168f5560cff0ef3ff53dc82da8afc5547e035812c18bee79ae2bb1a33546b65b",
+			},
+		},
+		{
+			name:          "drand and url",
+			extRandomness: [][]byte{drandRandomness, []byte("imexternalrandomness")},
+			expected: []string{
+				"0x19e85ce022cbd53729c9a95dc00fee068bb96244710af4c510d4c8eaec90c2fb",
+				"0x2b33cb0e00ab87efc48260443ef7e9cf4dd475700fdc7c83d4d6d7487417c51a",
+				"0x5ed8e6e52e6887cf104f617905c8ef0186a110534347ac309af15e433deaabf0",
+				"0x289b09d6eb74753570a31b4576166ddd129b6d39c3c6426596a6edf2d43fc535",
+			},
+		},
+		{
+			// Used to reduce to a zero secret when multiplying field elements.
+			name:          "all zeros",
+			extRandomness: [][]byte{make([]byte, 32)},
+			expected: []string{
+				"0x4acab013d9a655b2f71b6822b2be3e1379214ab7e9802e993e02b2e519256f8e",
+				"0x16ada0ef9f9b9657910403b6ccc031ab9833a13d93e0e3a29d591d014d129a40",
+				"0x67d7c16a9f666b477f5cf350e647c0319ed966f279a13110263e145e98fc9598",
+				"0x6006f38b42561b8b705ae171dd913e956489a3f8cfc4fa033c4ca7010e9131b1",
+			},
+		},
+		{
+			name:          "empty body",
+			extRandomness: [][]byte{{}},
+			expected: []string{
+				"0x3f43c446f4dc7d74fb7dfd2e572d89022b020acdbfdfd267be5b56b85b8b7c1e",
+				"0x3ac0cd6b816d470d8b0e7e4ed3054de6bcc76333f46ca8059143e11619b11084",
+				"0x0a51773a4392984ca9d9d7dee5401d480fb0647af6e7dfbb878dd2ee962fb028",
+				"0x1ea94d70e74d9150576267dc2d42d5e246eb4f75cd5b6f36ce75d839ec612add",
+			},
+		},
+	}
+
+	for _, subTest := range subTests {
+		subTest := subTest
+		t.Run(subTest.name, func(t *testing.T) {
+			t.Parallel()
+
+			secrets, err := deriveSecrets(csrng, subTest.extRandomness, len(subTest.expected))
+			require.NoError(t, err)
+			for i := range secrets {
+				secretBytes := secrets[i].Bytes()
+				require.Equal(t, subTest.expected[i], "0x"+hex.EncodeToString(secretBytes[:]))
+			}
+		})
+	}
+}
+
+func TestZeroSecret(t *testing.T) {
+	t.Parallel()
+
+	bc := newTestBatchContribution()
+	err := bc.contributeWithSecrets("", []string{"0x01", "0x00", "0x02", "0x03"})
+	require.Error(t, err)
+}
+
+func TestSecretsCleanupOnError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		identity string
+		secrets  []uint64
+	}{
+		{name: "invalid identity", identity: "invalid", secrets: []uint64{1, 2, 3, 4}},
+		{name: "secrets length mismatch", secrets: []uint64{1, 2, 3}},
+		{name: "zero secret", secrets: []uint64{1, 0, 2, 3}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			frs := make([]*bls12381Fr.Element, len(test.secrets))
+			for i := range test.secrets {
+				frs[i] = new(bls12381Fr.Element).SetUint64(test.secrets[i])
+			}
+			bc := newTestBatchContribution()
+			require.Error(t, bc.contributeWithFrs(test.identity, frs))
+			for i := range frs {
+				require.True(t, frs[i].IsZero())
+			}
+		})
+	}
+}
+
+func TestBLSSignature(t *testing.T) {
+	t.Parallel()
+
+	identity := "git|6136245|jsign"
+	hashedIdentity, err := HashIdentity(identity)
+	require.NoError(t, err)
+
+	bc := newTestBatchContribution()
+	err = bc.Contribute(identity)
+	require.NoError(t, err)
+
+	// Every sub-ceremony signature must satisfy e(signature, g2) == e(H(identity), potPubKey).
+	checkSignatures := func(bc *BatchContribution) {
+		for _, contribution := range bc.Contributions {
+			require.NotNil(t, contribution.BLSSignature)
+			ok, err := bls12381.PairingCheck(
+				[]bls12381.G1Affine{*contribution.BLSSignature, hashedIdentity},
+				[]bls12381.G2Affine{g2Generator, *new(bls12381.G2Affine).Neg(&contribution.PotPubKey)})
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+	}
+	checkSignatures(bc)
+
+	// The signatures must survive a JSON round-trip.
+	bcJSON, err := Encode(bc, false)
+	require.NoError(t, err)
+	decodedBc, err := DecodeBatchContribution(bcJSON)
+	require.NoError(t, err)
+	checkSignatures(decodedBc)
+
+	// Contributing without an identity doesn't produce signatures.
+	bc = newTestBatchContribution()
+	err = bc.Contribute("")
+	require.NoError(t, err)
+	for _, contribution := range bc.Contributions {
+		require.Nil(t, contribution.BLSSignature)
+	}
+}
+
+func TestValidateIdentity(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateIdentity("eth|0x5afe36d82de8990b777f82651b96608ec54d190d"))
+	require.NoError(t, ValidateIdentity("git|6136245|jsign"))
+
+	require.Error(t, ValidateIdentity(""))
+	require.Error(t, ValidateIdentity("eth|0x5afE36d82dE8990B777f82651B96608Ec54d190d"))
+	require.Error(t, ValidateIdentity("eth|5afe36d82de8990b777f82651b96608ec54d190d"))
+	require.Error(t, ValidateIdentity("eth|0x5afe36"))
+	require.Error(t, ValidateIdentity("git|jsign"))
+	require.Error(t, ValidateIdentity("git|jsign|6136245"))
+	require.Error(t, ValidateIdentity("foo|bar"))
+
+	bc := newTestBatchContribution()
+	require.Error(t, bc.Contribute("git|jsign"))
+}
+
+func TestBinary(t *testing.T) {
+	t.Parallel()
+
+	bc := newTestBatchContribution()
+	require.NoError(t, bc.Contribute("git|1|alice"))
+	bc.Contributions[2].BLSSignature = nil
+	bc.ECDSASignature = make([]byte, 65)
+	bc.ECDSASignature[64] = 27
+
+	for _, uncompressed := range []bool{false, true} {
+		encoded, err := EncodeBinary(bc, uncompressed)
+		require.NoError(t, err)
+		require.True(t, IsBinary(encoded))
+		decoded, err := DecodeBinaryBatchContribution(encoded)
+		require.NoError(t, err)
+		require.Equal(t, bc, decoded)
+	}
+
+	encoded, err := EncodeBinary(bc, false)
+	require.NoError(t, err)
+	jsonEncoded, err := Encode(bc, false)
+	require.NoError(t, err)
+	require.False(t, IsBinary(jsonEncoded))
+	require.Less(t, len(encoded), len(jsonEncoded)/2)
+
+	// withChecksum fixes the checksum after tampering the content, so the decoder checks are reached.
+	withChecksum := func(b []byte) []byte {
+		checksum := sha256.Sum256(b[:len(b)-sha256.Size])
+		copy(b[len(b)-sha256.Size:], checksum[:])
+		return b
+	}
+	g1Offset := binaryHeaderSize + len(bc.Contributions)*binaryContributionHeaderSize + bls12381.SizeOfG1AffineCompressed
+	tests := []struct {
+		name   string
+		tamper func(b []byte) []byte
+	}{
+		{name: "corrupted", tamper: func(b []byte) []byte { b[g1Offset+5] ^= 1; return b }},
+		{name: "truncated", tamper: func(b []byte) []byte { return withChecksum(append(b[:g1Offset], b[len(b)-sha256.Size:]...)) }},
+		{name: "too short", tamper: func(b []byte) []byte { return b[:binaryHeaderSize] }},
+		{name: "unknown version", tamper: func(b []byte) []byte { b[len(binaryMagic)] = 2; return withChecksum(b) }},
+		{name: "unknown flags", tamper: func(b []byte) []byte { b[len(binaryMagic)+1] |= 1 << 7; return withChecksum(b) }},
+		{name: "not in subgroup", tamper: func(b []byte) []byte {
+			var p bls12381.G1Affine
+			p.X.SetOne()
+			p.Y.SetOne()
+			pBytes := p.Bytes()
+			copy(b[g1Offset:], pBytes[:])
+			return withChecksum(b)
+		}},
+		{name: "mixed compression", tamper: func(b []byte) []byte { b[len(binaryMagic)+1] |= binaryFlagUncompressed; return withChecksum(b) }},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			tampered := test.tamper(append([]byte(nil), encoded...))
+			_, err := DecodeBinaryBatchContribution(tampered)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	t.Parallel()
+
+	bc := newTestBatchContribution()
+	require.NoError(t, bc.Contribute("git|1|alice"))
+	encoded, err := Encode(bc, false)
+	require.NoError(t, err)
+	require.NoError(t, SchemaCheck(encoded))
+
+	// tamper returns the encoded batch contribution after modifying its JSON representation.
+	tamper := func(f func(bcJSON *batchContributionJSON)) []byte {
+		var bcJSON batchContributionJSON
+		require.NoError(t, json.Unmarshal(encoded, &bcJSON))
+		f(&bcJSON)
+		b, err := json.Marshal(bcJSON)
+		require.NoError(t, err)
+		return b
+	}
+	tests := []struct {
+		name    string
+		json    []byte
+		err     error
+		message string
+	}{
+		{
+			name: "missing 0x prefix",
+			json: tamper(func(bcJSON *batchContributionJSON) {
+				bcJSON.Contributions[2].PowersOfTau.G1Powers[17] = bcJSON.Contributions[2].PowersOfTau.G1Powers[17][2:]
+			}),
+			err:     ErrMissingHexPrefix,
+			message: "contributions[2].powersOfTau.G1Powers[17]: missing 0x prefix",
+		},
+		{
+			name:    "short hex",
+			json:    tamper(func(bcJSON *batchContributionJSON) { bcJSON.Contributions[1].PowersOfTau.G2Powers[3] = "0x" }),
+			err:     ErrInvalidHex,
+			message: "contributions[1].powersOfTau.G2Powers[3]: invalid hex: got 0 hex characters but 192 were expected",
+		},
+		{
+			name: "uppercase hex",
+			json: tamper(func(bcJSON *batchContributionJSON) {
+				bcJSON.Contributions[0].PotPubKey = strings.ToUpper(bcJSON.Contributions[0].PotPubKey)
+			}),
+			err:     ErrMissingHexPrefix,
+			message: "contributions[0].potPubkey: missing 0x prefix",
+		},
+		{
+			name: "invalid hex character",
+			json: tamper(func(bcJSON *batchContributionJSON) {
+				bcJSON.Contributions[3].BLSSignature = "0x" + strings.Repeat("A", 2*bls12381.SizeOfG1AffineCompressed)
+			}),
+			err:     ErrInvalidHex,
+			message: "contributions[3].blsSignature: invalid hex: unexpected character 'A'",
+		},
+		{
+			name:    "missing potPubkey",
+			json:    tamper(func(bcJSON *batchContributionJSON) { bcJSON.Contributions[0].PotPubKey = "" }),
+			err:     ErrMissingHexPrefix,
+			message: "contributions[0].potPubkey: missing 0x prefix",
+		},
+		{
+			name:    "missing powers",
+			json:    tamper(func(bcJSON *batchContributionJSON) { bcJSON.Contributions[1].PowersOfTau.G1Powers = nil }),
+			err:     ErrInvalidType,
+			message: "contributions[1].powersOfTau: invalid type: missing powers",
+		},
+		{
+			name:    "invalid ecdsa signature",
+			json:    tamper(func(bcJSON *batchContributionJSON) { bcJSON.ECDSASignature = "0x1234" }),
+			err:     ErrInvalidHex,
+			message: "ecdsaSignature: invalid hex: got 4 hex characters but 130 were expected",
+		},
+		{
+			name:    "missing contributions",
+			json:    []byte(`{}`),
+			err:     ErrInvalidType,
+			message: "contributions: invalid type: missing contributions",
+		},
+		{
+			name:    "invalid type",
+			json:    []byte(`{"contributions":[{"numG1Powers":"16"}]}`),
+			err:     ErrInvalidType,
+			message: "numG1Powers: invalid type: got string but int was expected",
+		},
+		{
+			name: "not a point",
+			json: tamper(func(bcJSON *batchContributionJSON) {
+				bcJSON.Contributions[2].PowersOfTau.G1Powers[5] = "0x" + strings.Repeat("ab", bls12381.SizeOfG1AffineCompressed)
+			}),
+			err:     ErrInvalidPoint,
+			message: "contributions[2].powersOfTau.G1Powers[5]: invalid point",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := DecodeBatchContribution(test.json)
+			require.ErrorIs(t, err, test.err)
+			require.ErrorContains(t, err, test.message)
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+		})
+	}
+}
+
+func TestParameterCheck(t *testing.T) {
+	t.Parallel()
+
+	params := newTestBatchContribution().Parameters()
+	tests := []struct {
+		name    string
+		tamper  func(bc *BatchContribution)
+		message string
+	}{
+		{
+			name:   "valid",
+			tamper: func(bc *BatchContribution) {},
+		},
+		{
+			name:    "missing sub-ceremony",
+			tamper:  func(bc *BatchContribution) { bc.Contributions = bc.Contributions[:3] },
+			message: "contributions: parameter mismatch: there're 3 sub-ceremonies but 4 were expected",
+		},
+		{
+			name:    "declared powers",
+			tamper:  func(bc *BatchContribution) { bc.Contributions[1].NumG1Powers = 16 },
+			message: "contributions[1].numG1Powers: parameter mismatch: got 16 but 32 were expected",
+		},
+		{
+			name: "actual powers",
+			tamper: func(bc *BatchContribution) {
+				bc.Contributions[3].PowersOfTau.G2Affines = bc.Contributions[3].PowersOfTau.G2Affines[:4]
+			},
+			message: "contributions[3].powersOfTau.G2Powers: parameter mismatch: there're 4 powers but numG2Powers is 5",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			bc := newTestBatchContribution()
+			test.tamper(bc)
+			err := bc.ParameterCheck(params)
+			if test.message == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrParameterMismatch)
+			require.EqualError(t, err, test.message)
+
+			// Verify runs the parameter check against the previous batch contribution.
+			_, err = bc.Verify(newTestBatchContribution())
+			require.ErrorContains(t, err, test.message)
+		})
+	}
+}
+
+func FuzzDecodeBatchContribution(f *testing.F) {
+	bc := NewInitialBatchContribution([]SubCeremonyParameters{{NumG1Powers: 2, NumG2Powers: 2}})
+	require.NoError(f, bc.Contribute("git|1|alice"))
+	encoded, err := Encode(bc, false)
+	require.NoError(f, err)
+	f.Add(encoded)
+	f.Add([]byte(`{"contributions":[{"numG1Powers":2,"numG2Powers":2,"powersOfTau":{"G1Powers":["0x"],"G2Powers":[]}}]}`))
+	f.Add([]byte(`{"contributions":[{"potPubkey":"0"}]}`))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		bc, err := DecodeBatchContribution(b)
+		if err != nil {
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			return
+		}
+		// Anything that decodes must survive a round trip.
+		reencoded, err := Encode(bc, false)
+		require.NoError(t, err)
+		decoded, err := DecodeBatchContribution(reencoded)
+		require.NoError(t, err)
+		require.Equal(t, bc, decoded)
+	})
+}
+
+func BenchmarkDecodeBinary(b *testing.B) {
+	bc := NewInitialBatchContribution(CeremonyParameters)
+	jsonEncoded, err := Encode(bc, false)
+	require.NoError(b, err)
+	b.Run("json", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = DecodeBatchContribution(jsonEncoded)
+		}
+	})
+	for _, uncompressed := range []bool{false, true} {
+		encoded, err := EncodeBinary(bc, uncompressed)
+		require.NoError(b, err)
+		b.Run(fmt.Sprintf("binary uncompressed=%v", uncompressed), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = DecodeBinaryBatchContribution(encoded)
+			}
+		})
+	}
+}
+
 func BenchmarkDecodeJSON(b *testing.B) {
 	contributionFile, err := os.ReadFile("testdata/initialContribution.json")
 	require.NoError(b, err)
@@ -178,6 +883,17 @@ func BenchmarkContribute(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = bc.Contribute()
+		_ = bc.Contribute("")
 	}
 }
+
+// newTestBatchContribution returns a batch contribution with the same shape as the spec initial contribution
+// (i.e: all powers are the generators), but with fewer powers so tests are fast.
+func newTestBatchContribution() *BatchContribution {
+	return NewInitialBatchContribution([]SubCeremonyParameters{
+		{NumG1Powers: 16, NumG2Powers: 5},
+		{NumG1Powers: 32, NumG2Powers: 5},
+		{NumG1Powers: 64, NumG2Powers: 5},
+		{NumG1Powers: 128, NumG2Powers: 5},
+	})
+}
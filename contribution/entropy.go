@@ -0,0 +1,82 @@
+package contribution
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	bls12381Fr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+// secretDST is the domain separation tag used to derive sub-ceremony secrets from entropy sources.
+const secretDST = "KZG_CEREMONY_CONTRIBUTION_SECRET_V1_BLS12381FR_XMD:SHA-256"
+
+// csrngEntropyLength is the number of bytes pulled from the CSRNG, which is more than enough to have a
+// uniformly distributed secret after hashing to the scalar field.
+const csrngEntropyLength = 64
+
+// deriveSecrets derives one secret per sub-ceremony from the CSRNG output and the external entropy sources.
+// The i-th secret is computed with `hash_to_field` from RFC 9380 (expand_message_xmd with SHA-256, count=1):
+//
+//	msg_i    = I2OSP(i, 4) || I2OSP(len(csrng), 4) || csrng || I2OSP(len(ext_1), 4) || ext_1 || ...
+//	secret_i = hash_to_field(msg_i, secretDST)
+//
+// Prefixing the sub-ceremony index gives each sub-ceremony an independent secret, and length-prefixing every
+// source makes the encoding injective. Contrary to multiplying field elements, an external source can't cancel
+// the CSRNG entropy (e.g: bytes reducing to zero). A zero secret is rejected.
+func deriveSecrets(csrng []byte, extRandomness [][]byte, n int) ([]bls12381Fr.Element, error) {
+	msgLen := 4 + 4 + len(csrng)
+	for _, externalRandomness := range extRandomness {
+		msgLen += 4 + len(externalRandomness)
+	}
+	msg := make([]byte, 0, msgLen)
+	// Cleanup in-memory entropy.
+	defer func() {
+		msg = msg[:cap(msg)]
+		for j := range msg {
+			msg[j] = 0
+		}
+	}()
+
+	secrets := make([]bls12381Fr.Element, n)
+	for i := range secrets {
+		msg = binary.BigEndian.AppendUint32(msg[:0], uint32(i))
+		msg = appendLengthPrefixed(msg, csrng)
+		for _, externalRandomness := range extRandomness {
+			msg = appendLengthPrefixed(msg, externalRandomness)
+		}
+
+		frs, err := bls12381Fr.Hash(msg, []byte(secretDST), 1)
+		if err != nil {
+			wipeSecrets(secrets)
+			return nil, fmt.Errorf("hashing to field: %s", err)
+		}
+		if frs[0].IsZero() {
+			wipeSecrets(secrets)
+			return nil, fmt.Errorf("derived secret is zero")
+		}
+		secrets[i] = frs[0]
+		frs[0] = bls12381Fr.Element{}
+	}
+
+	return secrets, nil
+}
+
+func wipeSecrets(secrets []bls12381Fr.Element) {
+	for i := range secrets {
+		secrets[i] = bls12381Fr.Element{}
+	}
+}
+
+func appendLengthPrefixed(dst []byte, b []byte) []byte {
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(b)))
+	return append(dst, b...)
+}
+
+func csrngEntropy() ([]byte, error) {
+	entropy := make([]byte, csrngEntropyLength)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, fmt.Errorf("reading from CSRNG: %s", err)
+	}
+	return entropy, nil
+}
@@ -0,0 +1,58 @@
+package contribution
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+)
+
+// BLSSignatureDST is the domain separation tag used by the ceremony spec to hash participant identities
+// into G1 when signing them with the sub-ceremony secrets.
+const BLSSignatureDST = "BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_"
+
+// HashIdentity maps a participant identity (e.g: `eth|0x...` or `git|<id>|<handle>`) to a G1 point.
+func HashIdentity(identity string) (bls12381.G1Affine, error) {
+	p, err := bls12381.HashToG1([]byte(identity), []byte(BLSSignatureDST))
+	if err != nil {
+		return bls12381.G1Affine{}, fmt.Errorf("hashing identity to G1: %s", err)
+	}
+	return p, nil
+}
+
+// ValidateIdentity checks that the identity has the format expected by the sequencer. If that isn't the case,
+// any BLS signature of it wouldn't match the participant id recorded in the transcript.
+func ValidateIdentity(identity string) error {
+	parts := strings.Split(identity, "|")
+	switch parts[0] {
+	case "eth":
+		if len(parts) != 2 {
+			return fmt.Errorf("ethereum identity must have the format eth|0x<address>")
+		}
+		address := parts[1]
+		if !strings.HasPrefix(address, "0x") || len(address) != 42 {
+			return fmt.Errorf("ethereum address %s must be 0x prefixed and have 20 bytes", address)
+		}
+		if address != strings.ToLower(address) {
+			return fmt.Errorf("ethereum address %s must be lowercase", address)
+		}
+		if _, err := hex.DecodeString(address[2:]); err != nil {
+			return fmt.Errorf("ethereum address %s isn't valid hex: %s", address, err)
+		}
+	case "git":
+		if len(parts) != 3 {
+			return fmt.Errorf("github identity must have the format git|<id>|<handle>")
+		}
+		if _, err := strconv.ParseUint(parts[1], 10, 64); err != nil {
+			return fmt.Errorf("github id %s must be numeric", parts[1])
+		}
+		if parts[2] == "" {
+			return fmt.Errorf("github handle can't be empty")
+		}
+	default:
+		return fmt.Errorf("unknown identity type %s", parts[0])
+	}
+	return nil
+}
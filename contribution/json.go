@@ -1,11 +1,13 @@
 package contribution
 
 import (
-	"bytes"
 	_ "embed"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"golang.org/x/sync/errgroup"
@@ -21,29 +23,62 @@ type contributionJSON struct {
 	NumG2Powers int             `json:"numG2Powers"`
 	PowersOfTau powersOfTauJSON `json:"powersOfTau"`
 	PotPubKey   string          `json:"potPubkey"`
+	// BLSSignature is optional in the spec, so we omit it if the contribution wasn't signed.
+	BLSSignature string `json:"blsSignature,omitempty"`
 }
 type batchContributionJSON struct {
-	Contributions []contributionJSON `json:"contributions"`
+	Contributions  []contributionJSON `json:"contributions"`
+	ECDSASignature string             `json:"ecdsaSignature,omitempty"`
 }
 
+// DecodeBatchContribution decodes a batch contribution in the spec JSON format. It runs the `schema_check` and
+// the `subgroup_checks`, so all the points are valid. Errors are *ValidationError with the path of the
+// offending value.
 func DecodeBatchContribution(bcJSONBytes []byte) (*BatchContribution, error) {
+	bcJSON, err := unmarshalBatchContributionJSON(bcJSONBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcJSON.schemaCheck(); err != nil {
+		return nil, err
+	}
+
+	return bcJSON.decode()
+}
+
+func unmarshalBatchContributionJSON(bcJSONBytes []byte) (*batchContributionJSON, error) {
 	var bcJSON batchContributionJSON
 	if err := json.Unmarshal(bcJSONBytes, &bcJSON); err != nil {
-		return nil, fmt.Errorf("unmarshaling contribution content: %s", err)
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, &ValidationError{Path: typeErrorPath(typeErr.Field), Err: fmt.Errorf("%w: got %s but %s was expected", ErrInvalidType, typeErr.Value, typeErr.Type)}
+		}
+		return nil, &ValidationError{Err: fmt.Errorf("%w: unmarshaling contribution content: %s", ErrInvalidType, err)}
 	}
+	return &bcJSON, nil
+}
 
-	bc, err := bcJSON.decode()
-	if err != nil {
-		return nil, fmt.Errorf("subgroup checks: %s", err)
+// typeErrorPath converts the field of a json.UnmarshalTypeError to our path format. Newer Go versions include
+// array indexes as fields (e.g: `contributions.2.numG1Powers`), which are converted to `contributions[2].numG1Powers`.
+func typeErrorPath(field string) string {
+	var path string
+	for _, name := range strings.Split(field, ".") {
+		if i, err := strconv.Atoi(name); err == nil {
+			path = IndexPath(path, i)
+			continue
+		}
+		path = JoinPath(path, name)
 	}
-
-	return bc, nil
+	return path
 }
 
 func Encode(bc *BatchContribution, pretty bool) ([]byte, error) {
 	bcJSON := batchContributionJSON{
 		Contributions: make([]contributionJSON, len(bc.Contributions)),
 	}
+	if len(bc.ECDSASignature) > 0 {
+		bcJSON.ECDSASignature = "0x" + hex.EncodeToString(bc.ECDSASignature)
+	}
 	for i := range bc.Contributions {
 		potPubKeyBytes := bc.Contributions[i].PotPubKey.Bytes()
 		potPubKeyHex := "0x" + hex.EncodeToString(potPubKeyBytes[:])
@@ -56,6 +91,10 @@ func Encode(bc *BatchContribution, pretty bool) ([]byte, error) {
 				G2Powers: make([]string, len(bc.Contributions[i].PowersOfTau.G2Affines)),
 			},
 		}
+		if bc.Contributions[i].BLSSignature != nil {
+			blsSignatureBytes := bc.Contributions[i].BLSSignature.Bytes()
+			bcJSON.Contributions[i].BLSSignature = "0x" + hex.EncodeToString(blsSignatureBytes[:])
+		}
 
 		for j := range bc.Contributions[i].PowersOfTau.G1Affines {
 			gBytes := bc.Contributions[i].PowersOfTau.G1Affines[j].Bytes()
@@ -84,25 +123,24 @@ func Encode(bc *BatchContribution, pretty bool) ([]byte, error) {
 	return ret, nil
 }
 
+// decode decodes the points of a batch contribution that passed the schema check.
 func (bc *batchContributionJSON) decode() (*BatchContribution, error) {
 	ret := BatchContribution{
 		Contributions: make([]Contribution, len(bc.Contributions)),
 	}
+	if bc.ECDSASignature != "" {
+		ecdsaSignature, err := SchemaCheckHex("ecdsaSignature", bc.ECDSASignature, ecdsaSignatureSize)
+		if err != nil {
+			return nil, err
+		}
+		ret.ECDSASignature = ecdsaSignature
+	}
 
 	var group errgroup.Group
 	for i, contribution := range bc.Contributions {
 		i, contribution := i, contribution
+		path := IndexPath("contributions", i)
 		group.Go(func() error {
-			potPubKeyBytes, err := hex.DecodeString(contribution.PotPubKey[2:])
-			if err != nil {
-				return fmt.Errorf("hex decoding pot pubkey: %s", err)
-			}
-			decoder := bls12381.NewDecoder(bytes.NewReader(potPubKeyBytes))
-			var potPubKey bls12381.G2Affine
-			if err := decoder.Decode(&potPubKey); err != nil {
-				return fmt.Errorf("decoding public key into G2: %s", err)
-			}
-
 			ret.Contributions[i] = Contribution{
 				NumG1Powers: contribution.NumG1Powers,
 				NumG2Powers: contribution.NumG2Powers,
@@ -110,42 +148,55 @@ func (bc *batchContributionJSON) decode() (*BatchContribution, error) {
 					G1Affines: make([]bls12381.G1Affine, len(contribution.PowersOfTau.G1Powers)),
 					G2Affines: make([]bls12381.G2Affine, len(contribution.PowersOfTau.G2Powers)),
 				},
-				PotPubKey: potPubKey,
 			}
+			c := &ret.Contributions[i]
 
-			for j, g1Power := range contribution.PowersOfTau.G1Powers {
-				g1PowerBinary, err := hex.DecodeString(g1Power[2:])
+			potPubKeyPath := JoinPath(path, "potPubkey")
+			potPubKeyBytes, err := SchemaCheckHex(potPubKeyPath, contribution.PotPubKey, bls12381.SizeOfG2AffineCompressed)
+			if err != nil {
+				return err
+			}
+			if err := DecodeG2Point(potPubKeyPath, potPubKeyBytes, &c.PotPubKey); err != nil {
+				return err
+			}
+
+			if contribution.BLSSignature != "" {
+				blsSignaturePath := JoinPath(path, "blsSignature")
+				blsSignatureBytes, err := SchemaCheckHex(blsSignaturePath, contribution.BLSSignature, bls12381.SizeOfG1AffineCompressed)
 				if err != nil {
-					return fmt.Errorf("hex decoding %d-th g1 power in %d contribution: %s", j, i, err)
+					return err
+				}
+				c.BLSSignature = &bls12381.G1Affine{}
+				if err := DecodeG1Point(blsSignaturePath, blsSignatureBytes, c.BLSSignature); err != nil {
+					return err
 				}
+			}
 
-				// By default the Decoder *will do* subgroup checking.
-				decoder := bls12381.NewDecoder(bytes.NewReader(g1PowerBinary))
-				var g1Point bls12381.G1Affine
-				if err := decoder.Decode(&g1Point); err != nil {
-					return fmt.Errorf("decoding g1 point: %s", err)
+			for j, g1Power := range contribution.PowersOfTau.G1Powers {
+				g1PowerPath := IndexPath(JoinPath(path, "powersOfTau.G1Powers"), j)
+				g1PowerBytes, err := SchemaCheckHex(g1PowerPath, g1Power, bls12381.SizeOfG1AffineCompressed)
+				if err != nil {
+					return err
+				}
+				if err := DecodeG1Point(g1PowerPath, g1PowerBytes, &c.PowersOfTau.G1Affines[j]); err != nil {
+					return err
 				}
-				ret.Contributions[i].PowersOfTau.G1Affines[j] = g1Point
 			}
 			for j, g2Power := range contribution.PowersOfTau.G2Powers {
-				g2PowerBinary, err := hex.DecodeString(g2Power[2:])
+				g2PowerPath := IndexPath(JoinPath(path, "powersOfTau.G2Powers"), j)
+				g2PowerBytes, err := SchemaCheckHex(g2PowerPath, g2Power, bls12381.SizeOfG2AffineCompressed)
 				if err != nil {
-					return fmt.Errorf("hex decoding %d-th g2 power in %d contribution: %s", j, i, err)
+					return err
 				}
-
-				// By default the Decoder *will do* subgroup checking.
-				decoder := bls12381.NewDecoder(bytes.NewReader(g2PowerBinary))
-				var g2Point bls12381.G2Affine
-				if err := decoder.Decode(&g2Point); err != nil {
-					return fmt.Errorf("decoding g2 point: %s", err)
+				if err := DecodeG2Point(g2PowerPath, g2PowerBytes, &c.PowersOfTau.G2Affines[j]); err != nil {
+					return err
 				}
-				ret.Contributions[i].PowersOfTau.G2Affines[j] = g2Point
 			}
 			return nil
 		})
 	}
 	if err := group.Wait(); err != nil {
-		return nil, fmt.Errorf("decoding contribution :%s", err)
+		return nil, err
 	}
 
 	return &ret, nil
@@ -0,0 +1,45 @@
+package contribution
+
+import (
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+)
+
+// SubCeremonyParameters are the number of powers of a sub-ceremony.
+type SubCeremonyParameters struct {
+	NumG1Powers int
+	NumG2Powers int
+}
+
+// CeremonyParameters are the parameters of the sub-ceremonies of the Ethereum KZG ceremony.
+var CeremonyParameters = []SubCeremonyParameters{
+	{NumG1Powers: 4096, NumG2Powers: 65},
+	{NumG1Powers: 8192, NumG2Powers: 65},
+	{NumG1Powers: 16384, NumG2Powers: 65},
+	{NumG1Powers: 32768, NumG2Powers: 65},
+}
+
+// NewInitialBatchContribution returns the initial state of a ceremony with the provided parameters, where all the
+// powers and potPubKeys are the generators.
+func NewInitialBatchContribution(params []SubCeremonyParameters) *BatchContribution {
+	bc := &BatchContribution{
+		Contributions: make([]Contribution, len(params)),
+	}
+	for i, p := range params {
+		bc.Contributions[i] = Contribution{
+			NumG1Powers: p.NumG1Powers,
+			NumG2Powers: p.NumG2Powers,
+			PowersOfTau: PowersOfTau{
+				G1Affines: make([]bls12381.G1Affine, p.NumG1Powers),
+				G2Affines: make([]bls12381.G2Affine, p.NumG2Powers),
+			},
+			PotPubKey: g2Generator,
+		}
+		for j := range bc.Contributions[i].PowersOfTau.G1Affines {
+			bc.Contributions[i].PowersOfTau.G1Affines[j] = g1Generator
+		}
+		for j := range bc.Contributions[i].PowersOfTau.G2Affines {
+			bc.Contributions[i].PowersOfTau.G2Affines[j] = g2Generator
+		}
+	}
+	return bc
+}
@@ -0,0 +1,85 @@
+package contribution
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	bls12381Fr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+// PowersCheckPairs folds the `g1_powers_check` and `g2_powers_check` equations of the spec with random scalars.
+// It returns (G1, G2) pairs such that the product of their pairings is one iff all the equations hold, except
+// with negligible probability:
+//
+//	e(Σ r_i·G1[i], G2[1]) · e(-Σ r_i·G1[i+1], g2) · e(G1[1], Σ s_i·G2[i]) · e(-g1, Σ s_i·G2[i+1]) == 1
+//
+// This allows checking all the powers with a few multi-scalar multiplications and a single multi-pairing,
+// instead of two pairings per power. See G1PowersCheckPairs and G2PowersCheckPairs to check them separately.
+func (pot *PowersOfTau) PowersCheckPairs() ([]bls12381.G1Affine, []bls12381.G2Affine, error) {
+	g1PairsG1, g1PairsG2, err := pot.G1PowersCheckPairs()
+	if err != nil {
+		return nil, nil, err
+	}
+	g2PairsG1, g2PairsG2, err := pot.G2PowersCheckPairs()
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(g1PairsG1, g2PairsG1...), append(g1PairsG2, g2PairsG2...), nil
+}
+
+// G1PowersCheckPairs folds the `g1_powers_check` equations e(G1[i], G2[1]) == e(G1[i+1], g2) with random scalars.
+func (pot *PowersOfTau) G1PowersCheckPairs() ([]bls12381.G1Affine, []bls12381.G2Affine, error) {
+	if len(pot.G1Affines) < 2 || len(pot.G2Affines) < 2 {
+		return nil, nil, fmt.Errorf("at least two G1 and G2 powers are needed")
+	}
+
+	r, err := randomScalars(len(pot.G1Affines) - 1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating G1 random scalars: %s", err)
+	}
+	var g1Left, g1Right bls12381.G1Affine
+	if _, err := g1Left.MultiExp(pot.G1Affines[:len(pot.G1Affines)-1], r, ecc.MultiExpConfig{}); err != nil {
+		return nil, nil, fmt.Errorf("calculating G1 left combination: %s", err)
+	}
+	if _, err := g1Right.MultiExp(pot.G1Affines[1:], r, ecc.MultiExpConfig{}); err != nil {
+		return nil, nil, fmt.Errorf("calculating G1 right combination: %s", err)
+	}
+	g1Right.Neg(&g1Right)
+
+	return []bls12381.G1Affine{g1Left, g1Right}, []bls12381.G2Affine{pot.G2Affines[1], g2Generator}, nil
+}
+
+// G2PowersCheckPairs folds the `g2_powers_check` equations e(G1[1], G2[i]) == e(g1, G2[i+1]) with random scalars.
+func (pot *PowersOfTau) G2PowersCheckPairs() ([]bls12381.G1Affine, []bls12381.G2Affine, error) {
+	if len(pot.G1Affines) < 2 || len(pot.G2Affines) < 2 {
+		return nil, nil, fmt.Errorf("at least two G1 and G2 powers are needed")
+	}
+
+	s, err := randomScalars(len(pot.G2Affines) - 1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating G2 random scalars: %s", err)
+	}
+	var g2Left, g2Right bls12381.G2Affine
+	if _, err := g2Left.MultiExp(pot.G2Affines[:len(pot.G2Affines)-1], s, ecc.MultiExpConfig{}); err != nil {
+		return nil, nil, fmt.Errorf("calculating G2 left combination: %s", err)
+	}
+	if _, err := g2Right.MultiExp(pot.G2Affines[1:], s, ecc.MultiExpConfig{}); err != nil {
+		return nil, nil, fmt.Errorf("calculating G2 right combination: %s", err)
+	}
+
+	var negG1Generator bls12381.G1Affine
+	negG1Generator.Neg(&g1Generator)
+
+	return []bls12381.G1Affine{pot.G1Affines[1], negG1Generator}, []bls12381.G2Affine{g2Left, g2Right}, nil
+}
+
+func randomScalars(n int) ([]bls12381Fr.Element, error) {
+	scalars := make([]bls12381Fr.Element, n)
+	for i := range scalars {
+		if _, err := scalars[i].SetRandom(); err != nil {
+			return nil, fmt.Errorf("get random Fr: %s", err)
+		}
+	}
+	return scalars, nil
+}
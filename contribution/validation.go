@@ -0,0 +1,206 @@
+package contribution
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+)
+
+var (
+	// ErrMissingHexPrefix is returned when a hex encoded value doesn't have a 0x prefix.
+	ErrMissingHexPrefix = errors.New("missing 0x prefix")
+	// ErrInvalidHex is returned when a value isn't lowercase hex encoded or doesn't have the expected length.
+	ErrInvalidHex = errors.New("invalid hex")
+	// ErrInvalidPoint is returned when a value isn't a valid point of the expected subgroup.
+	ErrInvalidPoint = errors.New("invalid point")
+	// ErrInvalidType is returned when a JSON value doesn't have the expected type.
+	ErrInvalidType = errors.New("invalid type")
+	// ErrParameterMismatch is returned when the number of sub-ceremonies or powers doesn't match the expected ones.
+	ErrParameterMismatch = errors.New("parameter mismatch")
+)
+
+// ValidationError is an error of a value identified by its JSON path, for example
+// `contributions[2].powersOfTau.G1Powers[17]`.
+type ValidationError struct {
+	Path string
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// JoinPath appends a field to a JSON path.
+func JoinPath(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// IndexPath appends an array index to a JSON path.
+func IndexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+// SchemaCheckHex checks that a value is a 0x prefixed lowercase hex string of size bytes, as required by the
+// spec schema, and returns the decoded bytes.
+func SchemaCheckHex(path string, value string, size int) ([]byte, error) {
+	b := make([]byte, size)
+	if err := DecodeHex(path, value, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// DecodeHex is like SchemaCheckHex but decodes the value into dst, which must have the expected size.
+func DecodeHex(path string, value string, dst []byte) error {
+	if !strings.HasPrefix(value, "0x") {
+		return &ValidationError{Path: path, Err: ErrMissingHexPrefix}
+	}
+	if len(value) != 2+2*len(dst) {
+		return &ValidationError{Path: path, Err: fmt.Errorf("%w: got %d hex characters but %d were expected", ErrInvalidHex, len(value)-2, 2*len(dst))}
+	}
+	for _, c := range value[2:] {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return &ValidationError{Path: path, Err: fmt.Errorf("%w: unexpected character %q", ErrInvalidHex, c)}
+		}
+	}
+	if _, err := hex.Decode(dst, []byte(value[2:])); err != nil {
+		return &ValidationError{Path: path, Err: fmt.Errorf("%w: %s", ErrInvalidHex, err)}
+	}
+	return nil
+}
+
+// DecodeG1Point decodes a G1 point that must use all the provided bytes, so compressed and uncompressed
+// encodings can't be mixed. The point is checked to be in the correct subgroup.
+func DecodeG1Point(path string, b []byte, p *bls12381.G1Affine) error {
+	// SetBytes *does* subgroup checking.
+	n, err := p.SetBytes(b)
+	if err != nil {
+		return &ValidationError{Path: path, Err: fmt.Errorf("%w: %s", ErrInvalidPoint, err)}
+	}
+	if n != len(b) {
+		return &ValidationError{Path: path, Err: fmt.Errorf("%w: the point uses %d bytes but %d were expected", ErrInvalidPoint, n, len(b))}
+	}
+	return nil
+}
+
+// DecodeG2Point is the G2 version of DecodeG1Point.
+func DecodeG2Point(path string, b []byte, p *bls12381.G2Affine) error {
+	// SetBytes *does* subgroup checking.
+	n, err := p.SetBytes(b)
+	if err != nil {
+		return &ValidationError{Path: path, Err: fmt.Errorf("%w: %s", ErrInvalidPoint, err)}
+	}
+	if n != len(b) {
+		return &ValidationError{Path: path, Err: fmt.Errorf("%w: the point uses %d bytes but %d were expected", ErrInvalidPoint, n, len(b))}
+	}
+	return nil
+}
+
+// SchemaCheck implements the spec `schema_check`, checking that the JSON batch contribution has the expected
+// structure and that all the values have the expected encoding. Points aren't decoded, see
+// DecodeBatchContribution.
+func SchemaCheck(bcJSONBytes []byte) error {
+	bcJSON, err := unmarshalBatchContributionJSON(bcJSONBytes)
+	if err != nil {
+		return err
+	}
+	return bcJSON.schemaCheck()
+}
+
+func (bc *batchContributionJSON) schemaCheck() error {
+	if bc.Contributions == nil {
+		return &ValidationError{Path: "contributions", Err: fmt.Errorf("%w: missing contributions", ErrInvalidType)}
+	}
+	for i, c := range bc.Contributions {
+		path := IndexPath("contributions", i)
+		if c.PowersOfTau.G1Powers == nil || c.PowersOfTau.G2Powers == nil {
+			return &ValidationError{Path: JoinPath(path, "powersOfTau"), Err: fmt.Errorf("%w: missing powers", ErrInvalidType)}
+		}
+		for j, g1Power := range c.PowersOfTau.G1Powers {
+			if _, err := SchemaCheckHex(IndexPath(JoinPath(path, "powersOfTau.G1Powers"), j), g1Power, bls12381.SizeOfG1AffineCompressed); err != nil {
+				return err
+			}
+		}
+		for j, g2Power := range c.PowersOfTau.G2Powers {
+			if _, err := SchemaCheckHex(IndexPath(JoinPath(path, "powersOfTau.G2Powers"), j), g2Power, bls12381.SizeOfG2AffineCompressed); err != nil {
+				return err
+			}
+		}
+		if _, err := SchemaCheckHex(JoinPath(path, "potPubkey"), c.PotPubKey, bls12381.SizeOfG2AffineCompressed); err != nil {
+			return err
+		}
+		if c.BLSSignature != "" {
+			if _, err := SchemaCheckHex(JoinPath(path, "blsSignature"), c.BLSSignature, bls12381.SizeOfG1AffineCompressed); err != nil {
+				return err
+			}
+		}
+	}
+	if bc.ECDSASignature != "" {
+		if _, err := SchemaCheckHex("ecdsaSignature", bc.ECDSASignature, ecdsaSignatureSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parameters returns the declared parameters of each sub-ceremony.
+func (bc *BatchContribution) Parameters() []SubCeremonyParameters {
+	params := make([]SubCeremonyParameters, len(bc.Contributions))
+	for i, c := range bc.Contributions {
+		params[i] = SubCeremonyParameters{NumG1Powers: c.NumG1Powers, NumG2Powers: c.NumG2Powers}
+	}
+	return params
+}
+
+// ParameterCheck implements the spec `parameter_check`, checking that the number of sub-ceremonies and their
+// declared number of powers match the ceremony parameters, and that the declared number of powers match the
+// actual number of powers.
+func (bc *BatchContribution) ParameterCheck(params []SubCeremonyParameters) error {
+	if len(bc.Contributions) != len(params) {
+		return &ValidationError{Path: "contributions", Err: fmt.Errorf("%w: there're %d sub-ceremonies but %d were expected", ErrParameterMismatch, len(bc.Contributions), len(params))}
+	}
+	for i := range bc.Contributions {
+		if err := bc.Contributions[i].parameterCheck(IndexPath("contributions", i), params[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Contribution) parameterCheck(path string, params SubCeremonyParameters) error {
+	return CheckPowersParameters(path, params, c.NumG1Powers, c.NumG2Powers, len(c.PowersOfTau.G1Affines), len(c.PowersOfTau.G2Affines))
+}
+
+// CheckPowersParameters checks that the declared and actual number of powers of a sub-ceremony match the
+// expected parameters.
+func CheckPowersParameters(path string, params SubCeremonyParameters, numG1Powers, numG2Powers, lenG1Powers, lenG2Powers int) error {
+	if numG1Powers != params.NumG1Powers {
+		return &ValidationError{Path: JoinPath(path, "numG1Powers"), Err: fmt.Errorf("%w: got %d but %d were expected", ErrParameterMismatch, numG1Powers, params.NumG1Powers)}
+	}
+	if numG2Powers != params.NumG2Powers {
+		return &ValidationError{Path: JoinPath(path, "numG2Powers"), Err: fmt.Errorf("%w: got %d but %d were expected", ErrParameterMismatch, numG2Powers, params.NumG2Powers)}
+	}
+	if lenG1Powers != numG1Powers {
+		return &ValidationError{Path: JoinPath(path, "powersOfTau.G1Powers"), Err: fmt.Errorf("%w: there're %d powers but numG1Powers is %d", ErrParameterMismatch, lenG1Powers, numG1Powers)}
+	}
+	if lenG2Powers != numG2Powers {
+		return &ValidationError{Path: JoinPath(path, "powersOfTau.G2Powers"), Err: fmt.Errorf("%w: there're %d powers but numG2Powers is %d", ErrParameterMismatch, lenG2Powers, numG2Powers)}
+	}
+	if numG1Powers < 2 || numG2Powers < 2 {
+		return &ValidationError{Path: path, Err: fmt.Errorf("%w: at least two G1 and G2 powers are needed", ErrParameterMismatch)}
+	}
+	return nil
+}
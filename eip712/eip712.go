@@ -0,0 +1,165 @@
+package eip712
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+)
+
+// PotPubKey is the public key of a sub-ceremony contribution, as included in the EIP-712 typed data
+// that Ethereum participants sign.
+type PotPubKey struct {
+	NumG1Powers int
+	NumG2Powers int
+	PotPubKey   bls12381.G2Affine
+}
+
+// TypedData returns the EIP-712 typed data defined in the ceremony spec for the provided potPubKeys.
+func TypedData(potPubKeys []PotPubKey) apitypes.TypedData {
+	pubKeys := make([]interface{}, len(potPubKeys))
+	for i, potPubKey := range potPubKeys {
+		potPubKeyBytes := potPubKey.PotPubKey.Bytes()
+		pubKeys[i] = map[string]interface{}{
+			"numG1Powers": big.NewInt(int64(potPubKey.NumG1Powers)),
+			"numG2Powers": big.NewInt(int64(potPubKey.NumG2Powers)),
+			"potPubkey":   potPubKeyBytes[:],
+		}
+	}
+
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+			},
+			"contributionPubkey": {
+				{Name: "numG1Powers", Type: "uint256"},
+				{Name: "numG2Powers", Type: "uint256"},
+				{Name: "potPubkey", Type: "bytes"},
+			},
+			"PoTPubkeys": {
+				{Name: "potPubkeys", Type: "contributionPubkey[]"},
+			},
+		},
+		PrimaryType: "PoTPubkeys",
+		Domain: apitypes.TypedDataDomain{
+			Name:    "Ethereum KZG Ceremony",
+			Version: "1.0",
+			ChainId: math.NewHexOrDecimal256(1),
+		},
+		Message: apitypes.TypedDataMessage{
+			"potPubkeys": pubKeys,
+		},
+	}
+}
+
+// Hash returns the EIP-712 hash of the typed data for the provided potPubKeys, which is what gets signed.
+func Hash(potPubKeys []PotPubKey) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(TypedData(potPubKeys))
+	if err != nil {
+		return nil, fmt.Errorf("hashing typed data: %s", err)
+	}
+	return hash, nil
+}
+
+// Sign signs the EIP-712 typed data for the provided potPubKeys. The signature is returned in the
+// [R || S || V] format with V being 27/28, as produced by Ethereum wallets.
+func Sign(key *ecdsa.PrivateKey, potPubKeys []PotPubKey) ([]byte, error) {
+	hash, err := Hash(potPubKeys)
+	if err != nil {
+		return nil, fmt.Errorf("get typed data hash: %s", err)
+	}
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		return nil, fmt.Errorf("signing typed data hash: %s", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+
+	return signature, nil
+}
+
+// SignBatchContribution signs the potPubKeys of the batch contribution, and attaches the signature to it.
+func SignBatchContribution(key *ecdsa.PrivateKey, bc *contribution.BatchContribution) error {
+	signature, err := Sign(key, PotPubKeysFromBatchContribution(bc))
+	if err != nil {
+		return fmt.Errorf("signing potPubKeys: %s", err)
+	}
+	bc.ECDSASignature = signature
+
+	return nil
+}
+
+// PotPubKeysFromBatchContribution returns the potPubKeys of every sub-ceremony of the batch contribution.
+func PotPubKeysFromBatchContribution(bc *contribution.BatchContribution) []PotPubKey {
+	potPubKeys := make([]PotPubKey, len(bc.Contributions))
+	for i, contribution := range bc.Contributions {
+		potPubKeys[i] = PotPubKey{
+			NumG1Powers: contribution.NumG1Powers,
+			NumG2Powers: contribution.NumG2Powers,
+			PotPubKey:   contribution.PotPubKey,
+		}
+	}
+	return potPubKeys
+}
+
+// RecoverAddress returns the address of the signer of the EIP-712 typed data for the provided potPubKeys.
+// The signature is expected in the [R || S || V] format, where V can be 0/1 or 27/28.
+func RecoverAddress(signature []byte, potPubKeys []PotPubKey) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("signature length is %d but should be %d", len(signature), crypto.SignatureLength)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	hash, err := Hash(potPubKeys)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("get typed data hash: %s", err)
+	}
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("recovering public key: %s", err)
+	}
+
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// DecodeSignature decodes a 0x prefixed hex signature. An empty string is decoded as a nil signature.
+func DecodeSignature(signature string) ([]byte, error) {
+	if signature == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(signature, "0x") {
+		return nil, fmt.Errorf("signature must be 0x prefixed")
+	}
+	sig, err := hex.DecodeString(signature[2:])
+	if err != nil {
+		return nil, fmt.Errorf("hex decoding signature: %s", err)
+	}
+	return sig, nil
+}
+
+// AddressFromIdentity returns the Ethereum address of an `eth|0x<address>` participant identity.
+// The second returned value is false if the identity isn't an Ethereum one.
+func AddressFromIdentity(identity string) (common.Address, bool) {
+	if !strings.HasPrefix(identity, "eth|") {
+		return common.Address{}, false
+	}
+	address := strings.TrimPrefix(identity, "eth|")
+	if !common.IsHexAddress(address) {
+		return common.Address{}, false
+	}
+	return common.HexToAddress(address), true
+}
@@ -0,0 +1,145 @@
+package eip712
+
+import (
+	"encoding/hex"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeType(t *testing.T) {
+	t.Parallel()
+
+	typedData := TypedData(testPotPubKeys())
+	require.Equal(t,
+		"PoTPubkeys(contributionPubkey[] potPubkeys)contributionPubkey(uint256 numG1Powers,uint256 numG2Powers,bytes potPubkey)",
+		string(typedData.EncodeType("PoTPubkeys")))
+}
+
+func TestRecoverAddress(t *testing.T) {
+	t.Parallel()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	potPubKeys := testPotPubKeys()
+	hash, err := Hash(potPubKeys)
+	require.NoError(t, err)
+	signature, err := crypto.Sign(hash, key)
+	require.NoError(t, err)
+
+	// Recovery must work with V in both 0/1 and 27/28 formats.
+	signer, err := RecoverAddress(signature, potPubKeys)
+	require.NoError(t, err)
+	require.Equal(t, address, signer)
+	signature[crypto.RecoveryIDOffset] += 27
+	signer, err = RecoverAddress(signature, potPubKeys)
+	require.NoError(t, err)
+	require.Equal(t, address, signer)
+
+	// Signing different potPubKeys recovers a different address.
+	potPubKeys[1].PotPubKey = potPubKeys[0].PotPubKey
+	signer, err = RecoverAddress(signature, potPubKeys)
+	require.NoError(t, err)
+	require.NotEqual(t, address, signer)
+
+	_, err = RecoverAddress(signature[:64], potPubKeys)
+	require.Error(t, err)
+}
+
+func TestSignBatchContribution(t *testing.T) {
+	t.Parallel()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	identity := "eth|" + strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+
+	_, _, g1Generator, g2Generator := bls12381.Generators()
+	bc := &contribution.BatchContribution{
+		Contributions: []contribution.Contribution{{
+			NumG1Powers: 4,
+			NumG2Powers: 2,
+			PowersOfTau: contribution.PowersOfTau{
+				G1Affines: []bls12381.G1Affine{g1Generator, g1Generator, g1Generator, g1Generator},
+				G2Affines: []bls12381.G2Affine{g2Generator, g2Generator},
+			},
+		}},
+	}
+	require.NoError(t, bc.Contribute(identity))
+	require.NoError(t, SignBatchContribution(key, bc))
+
+	// The signature must survive the JSON encoding sent to the sequencer.
+	bcJSON, err := contribution.Encode(bc, false)
+	require.NoError(t, err)
+	decodedBc, err := contribution.DecodeBatchContribution(bcJSON)
+	require.NoError(t, err)
+	require.Equal(t, bc.ECDSASignature, decodedBc.ECDSASignature)
+
+	signer, err := RecoverAddress(decodedBc.ECDSASignature, PotPubKeysFromBatchContribution(decodedBc))
+	require.NoError(t, err)
+	address, ok := AddressFromIdentity(identity)
+	require.True(t, ok)
+	require.Equal(t, address, signer)
+
+	// Contributing again invalidates the signature.
+	require.NoError(t, bc.Contribute(identity))
+	require.Nil(t, bc.ECDSASignature)
+}
+
+func TestLoadKeys(t *testing.T) {
+	t.Parallel()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	hexKeyPath := filepath.Join(t.TempDir(), "key.hex")
+	hexKey := "0x" + hex.EncodeToString(crypto.FromECDSA(key)) + "\n"
+	require.NoError(t, os.WriteFile(hexKeyPath, []byte(hexKey), 0600))
+	loadedKey, err := LoadHexKey(hexKeyPath)
+	require.NoError(t, err)
+	require.Equal(t, address, crypto.PubkeyToAddress(loadedKey.PublicKey))
+
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(key, "passw0rd")
+	require.NoError(t, err)
+	loadedKey, err = LoadKeystoreKey(account.URL.Path, "passw0rd")
+	require.NoError(t, err)
+	require.Equal(t, address, crypto.PubkeyToAddress(loadedKey.PublicKey))
+
+	_, err = LoadKeystoreKey(account.URL.Path, "wrong")
+	require.Error(t, err)
+}
+
+func TestAddressFromIdentity(t *testing.T) {
+	t.Parallel()
+
+	address, ok := AddressFromIdentity("eth|0x5afe36d82de8990b777f82651b96608ec54d190d")
+	require.True(t, ok)
+	require.Equal(t, "0x5afE36d82dE8990B777f82651B96608Ec54d190d", address.Hex())
+
+	_, ok = AddressFromIdentity("git|6136245|jsign")
+	require.False(t, ok)
+	_, ok = AddressFromIdentity("eth|0x5afe36")
+	require.False(t, ok)
+}
+
+func testPotPubKeys() []PotPubKey {
+	_, _, _, g2Generator := bls12381.Generators()
+	var potPubKey bls12381.G2Affine
+	potPubKey.ScalarMultiplication(&g2Generator, big.NewInt(2))
+
+	return []PotPubKey{
+		{NumG1Powers: 4096, NumG2Powers: 65, PotPubKey: g2Generator},
+		{NumG1Powers: 8192, NumG2Powers: 65, PotPubKey: potPubKey},
+	}
+}
@@ -0,0 +1,38 @@
+package eip712
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// LoadKeystoreKey loads the private key of an encrypted geth keystore file.
+func LoadKeystoreKey(path string, passphrase string) (*ecdsa.PrivateKey, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading keystore file: %s", err)
+	}
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting keystore file: %s", err)
+	}
+	return key.PrivateKey, nil
+}
+
+// LoadHexKey loads a raw hex encoded private key from a file. The key can be optionally 0x prefixed.
+func LoadHexKey(path string) (*ecdsa.PrivateKey, error) {
+	keyBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %s", err)
+	}
+	hexKey := strings.TrimPrefix(strings.TrimSpace(string(keyBytes)), "0x")
+	key, err := crypto.HexToECDSA(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("decoding hex key: %s", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,141 @@
+package sequencer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/transcript"
+)
+
+// maxContributionSize is the maximum accepted size of a contribution request body.
+const maxContributionSize = 64 << 20
+
+type errorResponse struct {
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error"`
+}
+
+// Handler returns an http.Handler serving the same HTTP API as the official sequencer.
+func (s *Sequencer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/info/status", s.handleStatus)
+	mux.HandleFunc("/info/current_state", s.handleCurrentState)
+	mux.HandleFunc("/lobby/try_contribute", s.handleTryContribute)
+	mux.HandleFunc("/contribute", s.handleContribute)
+	mux.HandleFunc("/contribute/abort", s.handleAbort)
+	return mux
+}
+
+func (s *Sequencer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Status())
+}
+
+func (s *Sequencer) handleCurrentState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := transcript.Encode(w, s.Transcript(), false); err != nil {
+		log.Printf("encoding current state: %s", err)
+	}
+}
+
+func (s *Sequencer) handleTryContribute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	bc, err := s.TryContribute(sessionID(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if bc == nil {
+		// The official sequencer answers with a 200 status code when the participant should keep waiting.
+		writeJSON(w, http.StatusOK, errorResponse{Code: codeAnotherContributionInProgress, Error: "another contribution in progress"})
+		return
+	}
+
+	bcJSON, err := contribution.Encode(bc, false)
+	if err != nil {
+		log.Printf("encoding current state: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(bcJSON)
+}
+
+func (s *Sequencer) handleContribute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxContributionSize))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: codeInvalidContribution, Error: err.Error()})
+		return
+	}
+	bc, err := contribution.DecodeBatchContribution(body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: codeInvalidContribution, Error: err.Error()})
+		return
+	}
+
+	receipt, err := s.Contribute(sessionID(r), bc)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, receipt)
+}
+
+func (s *Sequencer) handleAbort(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.Abort(sessionID(r)); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func sessionID(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var contributionErr *contributionError
+	switch {
+	case errors.Is(err, errUnknownSession):
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+	case errors.As(err, &contributionErr):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: contributionErr.code, Error: contributionErr.message})
+	default:
+		log.Printf("internal error: %s", err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "internal error"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("encoding response: %s", err)
+	}
+}
@@ -0,0 +1,367 @@
+package sequencer
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/eip712"
+	"github.com/jsign/go-kzg-ceremony-client/transcript"
+)
+
+const (
+	// DefaultComputeDeadline is the time a participant has to submit its contribution after being selected.
+	DefaultComputeDeadline = 180 * time.Second
+	// DefaultLobbyTimeout is the time after which a participant that isn't polling is not counted in the lobby.
+	DefaultLobbyTimeout = 30 * time.Second
+)
+
+// Error codes returned to participants, using the same naming as the official sequencer.
+const (
+	codeAnotherContributionInProgress = "TryContributeError::AnotherContributionInProgress"
+	codeAlreadyContributed            = "TryContributeError::AlreadyContributed"
+	codeNotUsersTurn                  = "ContributeError::NotUsersTurn"
+	codeInvalidContribution           = "ContributeError::InvalidContribution"
+)
+
+var (
+	errUnknownSession = errors.New("unknown session id")
+)
+
+// contributionError is an error that should be reported to the participant with a code.
+type contributionError struct {
+	code    string
+	message string
+}
+
+func (e *contributionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.message)
+}
+
+// Config is the configuration of a Sequencer.
+type Config struct {
+	// Parameters are the sub-ceremonies parameters of the initial transcript.
+	Parameters []contribution.SubCeremonyParameters
+	// ComputeDeadline is the time a participant has to submit its contribution after being selected.
+	ComputeDeadline time.Duration
+	// LobbyTimeout is the time after which a participant that isn't polling is not counted in the lobby.
+	LobbyTimeout time.Duration
+	// Key is the sequencer key used to sign contribution receipts. If nil, a new key is generated.
+	Key *ecdsa.PrivateKey
+}
+
+type session struct {
+	participantID string
+	lastSeen      time.Time
+	contributed   bool
+}
+
+// Sequencer coordinates a ceremony: it keeps a lobby of participants, gives the turn to contribute to one of
+// them at a time, verifies the submitted contributions and extends the transcript with them.
+type Sequencer struct {
+	computeDeadline time.Duration
+	lobbyTimeout    time.Duration
+	key             *ecdsa.PrivateKey
+	now             func() time.Time
+	// verified is called (if not nil) after verifying a contribution without holding the lock, for testing.
+	verified func(sessionID string)
+
+	lock             sync.Mutex
+	sessions         map[string]*session
+	batchTranscript  *transcript.BatchTranscript
+	currentSessionID string
+	deadline         time.Time
+}
+
+// New creates a Sequencer with an initial transcript built from the configured parameters.
+func New(config Config) (*Sequencer, error) {
+	if len(config.Parameters) == 0 {
+		return nil, fmt.Errorf("at least one sub-ceremony is required")
+	}
+	for i, params := range config.Parameters {
+		if params.NumG1Powers < 2 || params.NumG2Powers < 2 {
+			return nil, fmt.Errorf("%d-th sub-ceremony must have at least 2 G1 and G2 powers", i)
+		}
+	}
+	if config.ComputeDeadline == 0 {
+		config.ComputeDeadline = DefaultComputeDeadline
+	}
+	if config.LobbyTimeout == 0 {
+		config.LobbyTimeout = DefaultLobbyTimeout
+	}
+	if config.Key == nil {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, fmt.Errorf("generating sequencer key: %s", err)
+		}
+		config.Key = key
+	}
+
+	return &Sequencer{
+		computeDeadline: config.ComputeDeadline,
+		lobbyTimeout:    config.LobbyTimeout,
+		key:             config.Key,
+		now:             time.Now,
+		sessions:        map[string]*session{},
+		batchTranscript: transcript.NewBatchTranscript(config.Parameters),
+	}, nil
+}
+
+// Address returns the address of the key used to sign contribution receipts.
+func (s *Sequencer) Address() string {
+	return crypto.PubkeyToAddress(s.key.PublicKey).Hex()
+}
+
+// NewSession registers a participant and returns the session id it should use to contribute.
+func (s *Sequencer) NewSession(participantID string) (string, error) {
+	if err := contribution.ValidateIdentity(participantID); err != nil {
+		return "", fmt.Errorf("invalid participant id: %s", err)
+	}
+
+	var sessionIDBytes [16]byte
+	if _, err := rand.Read(sessionIDBytes[:]); err != nil {
+		return "", fmt.Errorf("generating session id: %s", err)
+	}
+	// Format as a UUIDv4, as the official sequencer does.
+	sessionIDBytes[6] = (sessionIDBytes[6] & 0x0f) | 0x40
+	sessionIDBytes[8] = (sessionIDBytes[8] & 0x3f) | 0x80
+	sessionID := fmt.Sprintf("%x-%x-%x-%x-%x", sessionIDBytes[0:4], sessionIDBytes[4:6], sessionIDBytes[6:8], sessionIDBytes[8:10], sessionIDBytes[10:])
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sessions[sessionID] = &session{participantID: participantID}
+
+	return sessionID, nil
+}
+
+// Status is the public status of the ceremony.
+type Status struct {
+	LobbySize        int    `json:"lobby_size"`
+	NumContributions int    `json:"num_contributions"`
+	SequencerAddress string `json:"sequencer_address"`
+}
+
+// Status returns the current status of the ceremony.
+func (s *Sequencer) Status() Status {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	var lobbySize int
+	for _, session := range s.sessions {
+		if !session.contributed && now.Sub(session.lastSeen) < s.lobbyTimeout {
+			lobbySize++
+		}
+	}
+
+	return Status{
+		LobbySize:        lobbySize,
+		NumContributions: len(s.batchTranscript.ParticipantIDs) - 1,
+		SequencerAddress: s.Address(),
+	}
+}
+
+// Transcript returns the current transcript of the ceremony. The returned transcript must not be modified.
+func (s *Sequencer) Transcript() *transcript.BatchTranscript {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.batchTranscript
+}
+
+// TryContribute asks for the turn to contribute. If it's the participant turn, the current state of the
+// ceremony is returned. If another participant is contributing, it returns a nil batch contribution and
+// the participant should retry later.
+func (s *Sequencer) TryContribute(sessionID string) (*contribution.BatchContribution, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return nil, errUnknownSession
+	}
+	if session.contributed {
+		return nil, &contributionError{code: codeAlreadyContributed, message: "the participant already contributed"}
+	}
+
+	now := s.now()
+	session.lastSeen = now
+	if s.currentSessionID != "" && s.currentSessionID != sessionID && now.Before(s.deadline) {
+		return nil, nil
+	}
+	// The turn is renewed if it expired, even if the participant had it, so it can still contribute.
+	if s.currentSessionID != sessionID || !now.Before(s.deadline) {
+		s.currentSessionID = sessionID
+		s.deadline = now.Add(s.computeDeadline)
+	}
+
+	return s.batchTranscript.CurrentBatchContribution(), nil
+}
+
+// Receipt is the content signed by the sequencer after accepting a contribution.
+type Receipt struct {
+	Identity string   `json:"identity"`
+	Witness  []string `json:"witness"`
+}
+
+// ContributionReceipt is the receipt of an accepted contribution, signed by the sequencer key.
+type ContributionReceipt struct {
+	Receipt   string `json:"receipt"`
+	Signature string `json:"signature"`
+}
+
+// Contribute verifies the contribution of the participant that has the turn to contribute, and extends the
+// transcript with it. Invalid BLS or ECDSA signatures are dropped instead of rejecting the contribution.
+func (s *Sequencer) Contribute(sessionID string, bc *contribution.BatchContribution) (*ContributionReceipt, error) {
+	s.lock.Lock()
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		s.lock.Unlock()
+		return nil, errUnknownSession
+	}
+	if s.currentSessionID != sessionID || !s.now().Before(s.deadline) {
+		s.lock.Unlock()
+		return nil, &contributionError{code: codeNotUsersTurn, message: "not the participant turn to contribute"}
+	}
+	// While the participant has the turn, nobody else can modify the transcript.
+	batchTranscript := s.batchTranscript
+	participantID := session.participantID
+	s.lock.Unlock()
+
+	// The expensive checks are done without holding the lock so the status and lobby keep being served. The
+	// contribution is applied to a copy, since the current transcript can be concurrently read.
+	newBatchTranscript := copyBatchTranscript(batchTranscript)
+	if err := newBatchTranscript.Apply(participantID, bc, validSignatures(participantID, bc)); err != nil {
+		return nil, &contributionError{code: codeInvalidContribution, message: err.Error()}
+	}
+
+	receipt, err := s.signReceipt(participantID, bc)
+	if err != nil {
+		return nil, fmt.Errorf("signing receipt: %s", err)
+	}
+	if s.verified != nil {
+		s.verified(sessionID)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.currentSessionID != sessionID || !s.now().Before(s.deadline) {
+		return nil, &contributionError{code: codeNotUsersTurn, message: "the compute deadline expired"}
+	}
+	// The turn might have been aborted and given back while verifying, and another contribution applied in
+	// between, so the verified contribution would replace it.
+	if s.batchTranscript != batchTranscript {
+		return nil, &contributionError{code: codeNotUsersTurn, message: "the transcript changed while verifying the contribution"}
+	}
+	s.batchTranscript = newBatchTranscript
+	s.currentSessionID = ""
+	session.contributed = true
+
+	return receipt, nil
+}
+
+// Abort releases the turn of the participant before submitting a contribution, e.g: it was interrupted, so
+// another participant can take it without waiting for the compute deadline.
+func (s *Sequencer) Abort(sessionID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.sessions[sessionID]; !ok {
+		return errUnknownSession
+	}
+	if s.currentSessionID != sessionID || !s.now().Before(s.deadline) {
+		return &contributionError{code: codeNotUsersTurn, message: "not the participant turn to contribute"}
+	}
+	s.currentSessionID = ""
+
+	return nil
+}
+
+// validSignatures returns the signatures of the contribution that are valid for the participant.
+func validSignatures(participantID string, bc *contribution.BatchContribution) transcript.Signatures {
+	var signatures transcript.Signatures
+	if address, ok := eip712.AddressFromIdentity(participantID); ok && bc.ECDSASignature != nil {
+		signer, err := eip712.RecoverAddress(bc.ECDSASignature, eip712.PotPubKeysFromBatchContribution(bc))
+		if err == nil && signer == address {
+			signatures.ECDSA = bc.ECDSASignature
+		}
+	}
+
+	hashedIdentity, err := contribution.HashIdentity(participantID)
+	if err != nil {
+		return signatures
+	}
+	var negG2Generator bls12381.G2Affine
+	_, _, _, g2Generator := bls12381.Generators()
+	negG2Generator.Neg(&g2Generator)
+	signatures.BLS = make([]*bls12381.G1Affine, len(bc.Contributions))
+	for i, c := range bc.Contributions {
+		if c.BLSSignature == nil {
+			continue
+		}
+		ok, err := bls12381.PairingCheck(
+			[]bls12381.G1Affine{*c.BLSSignature, hashedIdentity},
+			[]bls12381.G2Affine{negG2Generator, c.PotPubKey})
+		if err == nil && ok {
+			signatures.BLS[i] = c.BLSSignature
+		}
+	}
+
+	return signatures
+}
+
+// copyBatchTranscript returns a copy of the transcript that can be extended without modifying the original.
+// Slices are clipped to their length, so appending to them always allocates a new backing array.
+func copyBatchTranscript(bt *transcript.BatchTranscript) *transcript.BatchTranscript {
+	ret := &transcript.BatchTranscript{
+		Transcripts:                make([]transcript.Transcript, len(bt.Transcripts)),
+		ParticipantIDs:             bt.ParticipantIDs[:len(bt.ParticipantIDs):len(bt.ParticipantIDs)],
+		ParticipantECDSASignatures: bt.ParticipantECDSASignatures[:len(bt.ParticipantECDSASignatures):len(bt.ParticipantECDSASignatures)],
+	}
+	for i, tr := range bt.Transcripts {
+		witness := tr.Witness
+		tr.Witness = transcript.Witness{
+			RunningProducts: witness.RunningProducts[:len(witness.RunningProducts):len(witness.RunningProducts)],
+			PotPubKeys:      witness.PotPubKeys[:len(witness.PotPubKeys):len(witness.PotPubKeys)],
+			BLSSignatures:   witness.BLSSignatures[:len(witness.BLSSignatures):len(witness.BLSSignatures)],
+		}
+		ret.Transcripts[i] = tr
+	}
+	return ret
+}
+
+func (s *Sequencer) signReceipt(participantID string, bc *contribution.BatchContribution) (*ContributionReceipt, error) {
+	receipt := Receipt{
+		Identity: participantID,
+		Witness:  make([]string, len(bc.Contributions)),
+	}
+	for i, c := range bc.Contributions {
+		potPubKeyBytes := c.PotPubKey.Bytes()
+		receipt.Witness[i] = "0x" + hex.EncodeToString(potPubKeyBytes[:])
+	}
+	receiptJSON, err := json.Marshal(receipt)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling receipt: %s", err)
+	}
+
+	// The receipt is signed as an EIP-191 personal message.
+	signature, err := crypto.Sign(accounts.TextHash(receiptJSON), s.key)
+	if err != nil {
+		return nil, fmt.Errorf("signing receipt: %s", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+
+	return &ContributionReceipt{
+		Receipt:   string(receiptJSON),
+		Signature: hex.EncodeToString(signature),
+	}, nil
+}
@@ -0,0 +1,309 @@
+package sequencer
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/eip712"
+	"github.com/jsign/go-kzg-ceremony-client/sequencerclient"
+	"github.com/jsign/go-kzg-ceremony-client/transcript"
+	"github.com/stretchr/testify/require"
+)
+
+var testParameters = []contribution.SubCeremonyParameters{{NumG1Powers: 16, NumG2Powers: 5}, {NumG1Powers: 32, NumG2Powers: 5}}
+
+func TestCeremony(t *testing.T) {
+	t.Parallel()
+
+	seq, client := newTestSequencer(t, Config{Parameters: testParameters})
+	ctx := context.Background()
+
+	ethKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	ethIdentity := "eth|0x" + hex.EncodeToString(crypto.PubkeyToAddress(ethKey.PublicKey).Bytes())
+	gitIdentity := "git|1234|alice"
+	ethSessionID, err := seq.NewSession(ethIdentity)
+	require.NoError(t, err)
+	gitSessionID, err := seq.NewSession(gitIdentity)
+	require.NoError(t, err)
+
+	status, err := client.GetStatus(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, status.NumContributions)
+	require.Equal(t, seq.Address(), status.SequencerAddress)
+
+	// The first participant gets the turn, so the second one has to wait.
+	bc, ok, err := client.TryContribute(ctx, ethSessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, ok, err = client.TryContribute(ctx, gitSessionID)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	status, err = client.GetStatus(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, status.LobbySize)
+
+	require.NoError(t, bc.Contribute(ethIdentity))
+	require.NoError(t, eip712.SignBatchContribution(ethKey, bc))
+	receipt, err := client.Contribute(ctx, ethSessionID, bc)
+	require.NoError(t, err)
+	signature, err := hex.DecodeString(receipt.Signature)
+	require.NoError(t, err)
+	signature[crypto.RecoveryIDOffset] -= 27
+	pubKey, err := crypto.SigToPub(accounts.TextHash([]byte(receipt.Receipt)), signature)
+	require.NoError(t, err)
+	require.Equal(t, status.SequencerAddress, crypto.PubkeyToAddress(*pubKey).Hex())
+	parsedReceipt, err := receipt.Verify(status.SequencerAddress, bc)
+	require.NoError(t, err)
+	require.Equal(t, ethIdentity, parsedReceipt.Identity)
+
+	// Now it's the turn of the second participant.
+	bc, ok, err = client.TryContribute(ctx, gitSessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, bc.Contribute(gitIdentity))
+	_, err = client.Contribute(ctx, gitSessionID, bc)
+	require.NoError(t, err)
+
+	// Participants can't contribute twice.
+	_, _, err = client.TryContribute(ctx, ethSessionID)
+	require.Error(t, err)
+
+	status, err = client.GetStatus(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, status.NumContributions)
+	require.Equal(t, 0, status.LobbySize)
+
+	bt, err := client.GetCurrentTranscript(ctx)
+	require.NoError(t, err)
+	require.NoError(t, bt.Verify())
+	require.Equal(t, []string{"", ethIdentity, gitIdentity}, bt.ParticipantIDs)
+
+	ecdsaResults, err := bt.VerifyECDSASignatures()
+	require.NoError(t, err)
+	require.Equal(t, transcript.SignatureValid, ecdsaResults[1].Status)
+	require.Equal(t, transcript.SignatureAbsent, ecdsaResults[2].Status)
+	blsResults, err := bt.VerifyBLSSignatures()
+	require.NoError(t, err)
+	require.Equal(t, transcript.SignatureValid, blsResults[1].Status)
+	require.Equal(t, transcript.SignatureValid, blsResults[2].Status)
+}
+
+func TestComputeDeadline(t *testing.T) {
+	t.Parallel()
+
+	seq, client := newTestSequencer(t, Config{Parameters: testParameters, ComputeDeadline: time.Minute})
+	clock := &testClock{now: time.Now()}
+	seq.now = clock.Now
+	ctx := context.Background()
+
+	slowSessionID, err := seq.NewSession("git|1|slow")
+	require.NoError(t, err)
+	fastSessionID, err := seq.NewSession("git|2|fast")
+	require.NoError(t, err)
+
+	slowBC, ok, err := client.TryContribute(ctx, slowSessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	clock.Advance(59 * time.Second)
+	_, ok, err = client.TryContribute(ctx, fastSessionID)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// After the deadline, the turn can be taken by another participant.
+	clock.Advance(2 * time.Second)
+	fastBC, ok, err := client.TryContribute(ctx, fastSessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, slowBC.Contribute(""))
+	_, err = client.Contribute(ctx, slowSessionID, slowBC)
+	require.ErrorContains(t, err, codeNotUsersTurn)
+
+	require.NoError(t, fastBC.Contribute(""))
+	_, err = client.Contribute(ctx, fastSessionID, fastBC)
+	require.NoError(t, err)
+	require.Equal(t, 1, seq.Status().NumContributions)
+}
+
+func TestExpiredTurnRenewed(t *testing.T) {
+	t.Parallel()
+
+	seq, client := newTestSequencer(t, Config{Parameters: testParameters, ComputeDeadline: time.Minute})
+	clock := &testClock{now: time.Now()}
+	seq.now = clock.Now
+	ctx := context.Background()
+
+	sessionID, err := seq.NewSession("git|1|alice")
+	require.NoError(t, err)
+	_, ok, err := client.TryContribute(ctx, sessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// After the deadline, polling again gives a new deadline to the same participant.
+	clock.Advance(2 * time.Minute)
+	bc, ok, err := client.TryContribute(ctx, sessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	clock.Advance(30 * time.Second)
+
+	require.NoError(t, bc.Contribute(""))
+	_, err = client.Contribute(ctx, sessionID, bc)
+	require.NoError(t, err)
+	require.Equal(t, 1, seq.Status().NumContributions)
+}
+
+func TestAbort(t *testing.T) {
+	t.Parallel()
+
+	seq, client := newTestSequencer(t, Config{Parameters: testParameters, ComputeDeadline: time.Hour})
+	ctx := context.Background()
+
+	aliceSessionID, err := seq.NewSession("git|1|alice")
+	require.NoError(t, err)
+	bobSessionID, err := seq.NewSession("git|2|bob")
+	require.NoError(t, err)
+
+	aliceBC, ok, err := client.TryContribute(ctx, aliceSessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, ok, err = client.TryContribute(ctx, bobSessionID)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Only the participant with the turn can abort it.
+	var contributionErr *sequencerclient.ContributionError
+	require.ErrorAs(t, client.Abort(ctx, bobSessionID), &contributionErr)
+	require.Equal(t, codeNotUsersTurn, contributionErr.Code)
+	require.ErrorIs(t, client.Abort(ctx, "unknown-session"), sequencerclient.ErrUnauthorized)
+
+	// After aborting, the turn is given to the next participant without waiting for the compute deadline.
+	require.NoError(t, client.Abort(ctx, aliceSessionID))
+	bobBC, ok, err := client.TryContribute(ctx, bobSessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, aliceBC.Contribute(""))
+	_, err = client.Contribute(ctx, aliceSessionID, aliceBC)
+	require.ErrorAs(t, err, &contributionErr)
+	require.Equal(t, codeNotUsersTurn, contributionErr.Code)
+
+	require.NoError(t, bobBC.Contribute(""))
+	_, err = client.Contribute(ctx, bobSessionID, bobBC)
+	require.NoError(t, err)
+	require.Equal(t, 1, seq.Status().NumContributions)
+}
+
+func TestTranscriptReplacedWhileVerifying(t *testing.T) {
+	t.Parallel()
+
+	seq, client := newTestSequencer(t, Config{Parameters: testParameters, ComputeDeadline: time.Hour})
+	ctx := context.Background()
+
+	aliceSessionID, err := seq.NewSession("git|1|alice")
+	require.NoError(t, err)
+	bobSessionID, err := seq.NewSession("git|2|bob")
+	require.NoError(t, err)
+
+	// While the contribution of Alice is verified, she aborts her turn, Bob contributes and Alice gets the turn
+	// back, so the transcript Alice contributed to is replaced.
+	seq.verified = func(sessionID string) {
+		if sessionID != aliceSessionID {
+			return
+		}
+		require.NoError(t, seq.Abort(aliceSessionID))
+		bobBC, err := seq.TryContribute(bobSessionID)
+		require.NoError(t, err)
+		require.NotNil(t, bobBC)
+		require.NoError(t, bobBC.Contribute(""))
+		_, err = seq.Contribute(bobSessionID, bobBC)
+		require.NoError(t, err)
+		aliceBC, err := seq.TryContribute(aliceSessionID)
+		require.NoError(t, err)
+		require.NotNil(t, aliceBC)
+	}
+
+	aliceBC, ok, err := client.TryContribute(ctx, aliceSessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, aliceBC.Contribute(""))
+	_, err = client.Contribute(ctx, aliceSessionID, aliceBC)
+	var contributionErr *sequencerclient.ContributionError
+	require.ErrorAs(t, err, &contributionErr)
+	require.Equal(t, codeNotUsersTurn, contributionErr.Code)
+
+	// The contribution of Alice isn't applied onto the transcript with the contribution of Bob.
+	require.Equal(t, 1, seq.Status().NumContributions)
+	batchTranscript := seq.Transcript()
+	require.Equal(t, []string{"", "git|2|bob"}, batchTranscript.ParticipantIDs)
+	require.NoError(t, batchTranscript.Verify())
+}
+
+func TestInvalidContribution(t *testing.T) {
+	t.Parallel()
+
+	seq, client := newTestSequencer(t, Config{Parameters: testParameters})
+	ctx := context.Background()
+
+	_, _, err := client.TryContribute(ctx, "unknown-session")
+	require.ErrorIs(t, err, sequencerclient.ErrUnauthorized)
+	_, err = seq.NewSession("not-an-identity")
+	require.Error(t, err)
+
+	sessionID, err := seq.NewSession("git|1|alice")
+	require.NoError(t, err)
+	bc, ok, err := client.TryContribute(ctx, sessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, bc.Contribute(""))
+	potPubKey := bc.Contributions[0].PotPubKey
+	bc.Contributions[0].PotPubKey = bc.Contributions[1].PotPubKey
+	_, err = client.Contribute(ctx, sessionID, bc)
+	require.ErrorContains(t, err, codeInvalidContribution)
+
+	bc.Contributions[0].PotPubKey = potPubKey
+	bc.Contributions[1].PowersOfTau.G1Affines[3] = bc.Contributions[1].PowersOfTau.G1Affines[4]
+	_, err = client.Contribute(ctx, sessionID, bc)
+	require.ErrorContains(t, err, codeInvalidContribution)
+
+	require.Equal(t, 0, seq.Status().NumContributions)
+	require.NoError(t, seq.Transcript().Verify())
+}
+
+func newTestSequencer(t *testing.T, config Config) (*Sequencer, *sequencerclient.Client) {
+	seq, err := New(config)
+	require.NoError(t, err)
+	server := httptest.NewServer(seq.Handler())
+	t.Cleanup(server.Close)
+
+	client, err := sequencerclient.New(server.URL)
+	require.NoError(t, err)
+
+	return seq, client
+}
+
+type testClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
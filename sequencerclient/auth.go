@@ -0,0 +1,207 @@
+package sequencerclient
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// authErrorDescriptions are user friendly descriptions of the sequencer `AuthErrorPayload::*` error codes.
+var authErrorDescriptions = map[string]string{
+	"AuthErrorPayload::UserCreatedAfterDeadline": "your account doesn't match the sequencer minimal conditions. Ethereum addresses must have sent at least 3 transactions at block 15537393, and GitHub accounts must have a commit dated before 1 August 2022 00:00 UTC",
+	"AuthErrorPayload::InvalidAuthCode":          "the login link got stale, start the login process from scratch",
+	"AuthErrorPayload::UserAlreadyContributed":   "you can only contribute once per GitHub account or Ethereum address",
+	"AuthErrorPayload::LobbyIsFull":              "the lobby is full, try again later",
+	"AuthErrorPayload::FetchUserDataError":       "the sequencer couldn't fetch your account data from the identity provider, try again later",
+	"AuthErrorPayload::CouldNotExtractUserData":  "the sequencer couldn't read your account data from the identity provider",
+}
+
+// AuthError is an authentication error returned by the sequencer.
+type AuthError struct {
+	// Code is the sequencer error code, e.g: AuthErrorPayload::InvalidAuthCode.
+	Code    string
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	if description, ok := authErrorDescriptions[e.Code]; ok {
+		return fmt.Sprintf("%s (%s)", description, e.Code)
+	}
+	if e.Code == "" {
+		return fmt.Sprintf("authentication error: %s", e.Message)
+	}
+	return fmt.Sprintf("authentication error %s: %s", e.Code, e.Message)
+}
+
+// AuthLinks are the links to login with an Ethereum address or a GitHub account.
+type AuthLinks struct {
+	EthAuthURL    string `json:"eth_auth_url"`
+	GithubAuthURL string `json:"github_auth_url"`
+}
+
+// RequestAuthLinks requests the login links to the sequencer. If redirectTo isn't empty, the sequencer redirects
+// to it after the login with the session in the query parameters, see ParseAuthCallback.
+func (c *Client) RequestAuthLinks(ctx context.Context, redirectTo string) (AuthLinks, error) {
+	var links AuthLinks
+	err := c.retry(ctx, func() (err error) {
+		links, err = c.requestAuthLinks(ctx, redirectTo)
+		return err
+	})
+	return links, err
+}
+
+func (c *Client) requestAuthLinks(ctx context.Context, redirectTo string) (AuthLinks, error) {
+	path := "/auth/request_link"
+	if redirectTo != "" {
+		path += "?" + url.Values{"redirect_to": {redirectTo}}.Encode()
+	}
+	req, cancel, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return AuthLinks{}, err
+	}
+	defer cancel()
+
+	res, err := c.do(ctx, req)
+	if err != nil {
+		return AuthLinks{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var errorResponse struct {
+			Code  string `json:"code"`
+			Error string `json:"error"`
+		}
+		body, _ := io.ReadAll(res.Body)
+		if err := json.Unmarshal(body, &errorResponse); err != nil || (errorResponse.Code == "" && errorResponse.Error == "") {
+			return AuthLinks{}, statusError(res)
+		}
+		return AuthLinks{}, &AuthError{Code: errorResponse.Code, Message: errorResponse.Error}
+	}
+
+	var links AuthLinks
+	if err := json.NewDecoder(res.Body).Decode(&links); err != nil {
+		return AuthLinks{}, fmt.Errorf("decoding auth links: %s", err)
+	}
+	if links.EthAuthURL == "" || links.GithubAuthURL == "" {
+		return AuthLinks{}, fmt.Errorf("the sequencer didn't return the auth links")
+	}
+
+	return links, nil
+}
+
+// Session is the result of a successful login.
+type Session struct {
+	SessionID string
+	// Subject, Nickname and Provider identify the logged in account, if provided by the sequencer.
+	Subject  string
+	Nickname string
+	Provider string
+}
+
+// ParseAuthCallback parses the query parameters of the sequencer redirect after a login. A successful login has
+// a `session_id` parameter, while a failed one has the error code in the `code` or `error` parameter.
+func ParseAuthCallback(query url.Values) (*Session, error) {
+	if sessionID := query.Get("session_id"); sessionID != "" {
+		return &Session{
+			SessionID: sessionID,
+			Subject:   query.Get("sub"),
+			Nickname:  query.Get("nickname"),
+			Provider:  query.Get("provider"),
+		}, nil
+	}
+
+	authErr := &AuthError{Code: query.Get("code"), Message: query.Get("message")}
+	if errorParam := query.Get("error"); strings.HasPrefix(errorParam, "AuthErrorPayload::") && authErr.Code == "" {
+		authErr.Code = errorParam
+	} else if authErr.Message == "" {
+		authErr.Message = errorParam
+	}
+	if authErr.Code == "" && authErr.Message == "" {
+		authErr.Message = "the sequencer redirect doesn't have a session id"
+	}
+	return nil, authErr
+}
+
+// Login runs the login flow with a short-lived HTTP listener at listenAddr (e.g: 127.0.0.1:0) that receives the
+// sequencer redirect after the login. The login links are passed to openLinks, which should show or open the
+// chosen one in a browser. It returns when the redirect is received or ctx is done. Callbacks without the random
+// state of this login are rejected.
+func (c *Client) Login(ctx context.Context, listenAddr string, openLinks func(AuthLinks) error) (*Session, error) {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("listening for the login callback: %s", err)
+	}
+	defer listener.Close()
+
+	type result struct {
+		session *Session
+		err     error
+	}
+	// The random state in the callback path is only known by the sequencer redirect, so other local processes or
+	// websites can't complete the login with a session of their own. It's in the path instead of the query since
+	// the sequencer appends the session to the redirect URL.
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		return nil, fmt.Errorf("generating login state: %s", err)
+	}
+	state := hex.EncodeToString(stateBytes)
+
+	results := make(chan result, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(r.URL.Path, "/callback/")), []byte(state)) != 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Login failed: the callback doesn't have the state of the login started by kzgcli\n")
+			return
+		}
+		session, err := ParseAuthCallback(r.URL.Query())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Login failed: %s\n", err)
+		} else {
+			fmt.Fprintf(w, "Login succeeded! You can close this window and go back to kzgcli.\n")
+		}
+		// Only the first callback is considered.
+		select {
+		case results <- result{session: session, err: err}:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = server.Serve(listener) }()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	links, err := c.RequestAuthLinks(ctx, fmt.Sprintf("http://%s/callback/%s", listener.Addr(), state))
+	if err != nil {
+		return nil, fmt.Errorf("requesting auth links: %w", err)
+	}
+	if err := openLinks(links); err != nil {
+		return nil, fmt.Errorf("opening auth links: %s", err)
+	}
+
+	select {
+	case res := <-results:
+		return res.session, res.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out waiting for the login to finish")
+		}
+		return nil, ctx.Err()
+	}
+}
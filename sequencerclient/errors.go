@@ -0,0 +1,90 @@
+package sequencerclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrUnauthorized is returned when the sequencer doesn't accept the session id, since it's wrong or expired.
+	ErrUnauthorized = errors.New("wrong or expired session-id, login again with the sequencer")
+	// ErrSequencerUnavailable is returned when the sequencer can't be reached or fails with a server error. It's
+	// usually temporary.
+	ErrSequencerUnavailable = errors.New("sequencer unavailable")
+	// ErrContributionUnconfirmed is returned when the contribution was sent but the sequencer response was lost or
+	// it failed with a server error, so it might have been accepted. It isn't retried, since sending the same
+	// contribution again isn't idempotent.
+	ErrContributionUnconfirmed = errors.New("the contribution was sent but the sequencer didn't confirm it, it might have been accepted")
+)
+
+// codeRateLimited is the error code of the sequencer when a participant calls too often.
+const codeRateLimited = "TryContributeError::RateLimited"
+
+// ContributionError is an error code returned by the sequencer when trying to contribute or sending a
+// contribution, e.g: the contribution is invalid or it isn't the participant turn.
+type ContributionError struct {
+	// Code is the sequencer error code, e.g: ContributeError::InvalidContribution.
+	Code    string
+	Message string
+}
+
+func (e *ContributionError) Error() string {
+	return fmt.Sprintf("contribution error (Code: %s, Error: %s)", e.Code, e.Message)
+}
+
+// ErrRateLimited is returned when the sequencer rate limits the calls. RetryAfter is the duration to wait before
+// calling again requested by the sequencer, or zero if it didn't request any.
+type ErrRateLimited struct {
+	RetryAfter time.Duration
+}
+
+func (e ErrRateLimited) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited by the sequencer, retry after %v", e.RetryAfter)
+	}
+	return "rate limited by the sequencer"
+}
+
+// IsTemporary returns true if the error is a rate limit or the sequencer is unavailable, so the same call might
+// succeed later.
+func IsTemporary(err error) bool {
+	var rateLimited ErrRateLimited
+	return errors.Is(err, ErrSequencerUnavailable) || errors.As(err, &rateLimited)
+}
+
+// statusError returns the error of a response with a non-OK status code that isn't specific of an endpoint.
+func statusError(res *http.Response) error {
+	switch {
+	case res.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case res.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited{RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"))}
+	case res.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w (status code %d)", ErrSequencerUnavailable, res.StatusCode)
+	default:
+		return fmt.Errorf("received status code %d", res.StatusCode)
+	}
+}
+
+// parseRetryAfter parses a Retry-After header value, which is either a number of seconds or an HTTP date. It
+// returns zero if the value is empty or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
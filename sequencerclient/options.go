@@ -0,0 +1,140 @@
+package sequencerclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultUserAgent is the User-Agent header sent in the requests to the sequencer.
+const DefaultUserAgent = "go-kzg-ceremony-client"
+
+// Option configures a Client, see New.
+type Option func(*options) error
+
+type options struct {
+	httpClient     *http.Client
+	timeout        time.Duration
+	userAgent      string
+	caCertificates []byte
+	pinnedSPKIs    [][sha256.Size]byte
+	retryPolicy    RetryPolicy
+}
+
+// WithHTTPClient sets the HTTP client used for the requests, which by default is http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) error {
+		if httpClient == nil {
+			return fmt.Errorf("the http client can't be nil")
+		}
+		o.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithTimeout sets the maximum duration of each call, including reading the response (e.g: downloading the
+// transcript). By default there's no timeout other than the context of the call.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) error {
+		if timeout < 0 {
+			return fmt.Errorf("the timeout can't be negative")
+		}
+		o.timeout = timeout
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests, which by default is DefaultUserAgent.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) error {
+		o.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithCACertificates trusts the PEM encoded CA certificates to verify the sequencer, in addition to the system
+// ones. This is needed behind TLS-inspecting gateways with their own CA.
+func WithCACertificates(pemCerts []byte) Option {
+	return func(o *options) error {
+		if !x509.NewCertPool().AppendCertsFromPEM(pemCerts) {
+			return fmt.Errorf("no PEM certificates found")
+		}
+		o.caCertificates = append(o.caCertificates, pemCerts...)
+		return nil
+	}
+}
+
+// WithPinnedSPKIs pins the sequencer certificates to the provided SHA-256 hashes of their DER encoded Subject
+// Public Key Info. The connection is only accepted if a certificate of the verified chain has one of them.
+func WithPinnedSPKIs(hashes ...[sha256.Size]byte) Option {
+	return func(o *options) error {
+		if len(hashes) == 0 {
+			return fmt.Errorf("at least one pin is needed")
+		}
+		o.pinnedSPKIs = append(o.pinnedSPKIs, hashes...)
+		return nil
+	}
+}
+
+// WithRetryPolicy sets the policy to retry failed calls, e.g: an ExponentialBackoff. By default, calls aren't
+// retried.
+func WithRetryPolicy(retryPolicy RetryPolicy) Option {
+	return func(o *options) error {
+		o.retryPolicy = retryPolicy
+		return nil
+	}
+}
+
+// buildHTTPClient returns the HTTP client configured with the TLS options. If there're TLS options, the
+// transport of the client must be an *http.Transport (or nil), which is cloned to not modify the original one.
+func (o *options) buildHTTPClient() (*http.Client, error) {
+	if o.caCertificates == nil && o.pinnedSPKIs == nil {
+		return o.httpClient, nil
+	}
+
+	var transport *http.Transport
+	switch t := o.httpClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, fmt.Errorf("custom CA certificates and pins need an *http.Transport but the http client has a %T", t)
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+
+	if o.caCertificates != nil {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		rootCAs.AppendCertsFromPEM(o.caCertificates)
+		transport.TLSClientConfig.RootCAs = rootCAs
+	}
+	if o.pinnedSPKIs != nil {
+		pins := o.pinnedSPKIs
+		transport.TLSClientConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, chain := range cs.VerifiedChains {
+				for _, cert := range chain {
+					spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+					for _, pin := range pins {
+						if bytes.Equal(spki[:], pin[:]) {
+							return nil
+						}
+					}
+				}
+			}
+			return fmt.Errorf("the sequencer certificates don't match any pinned public key")
+		}
+	}
+
+	httpClient := *o.httpClient
+	httpClient.Transport = transport
+	return &httpClient, nil
+}
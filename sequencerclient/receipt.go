@@ -0,0 +1,107 @@
+package sequencerclient
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+)
+
+// ErrInvalidReceipt is returned when a contribution receipt isn't signed by the sequencer or doesn't match the
+// submitted contribution.
+var ErrInvalidReceipt = errors.New("invalid receipt")
+
+// Receipt is the content of a contribution receipt: the identity of the participant and the potPubKeys of its
+// contribution in each sub-ceremony.
+type Receipt struct {
+	Identity string
+	Witness  []bls12381.G2Affine
+}
+
+type receiptJSON struct {
+	Identity string   `json:"identity"`
+	Witness  []string `json:"witness"`
+}
+
+// Parse decodes the receipt content. The witness points are subgroup checked.
+func (cr *ContributionReceipt) Parse() (*Receipt, error) {
+	var rJSON receiptJSON
+	if err := json.Unmarshal([]byte(cr.Receipt), &rJSON); err != nil {
+		return nil, fmt.Errorf("%w: unmarshaling receipt: %s", ErrInvalidReceipt, err)
+	}
+	if rJSON.Identity == "" {
+		return nil, fmt.Errorf("%w: the receipt doesn't have an identity", ErrInvalidReceipt)
+	}
+	receipt := &Receipt{
+		Identity: rJSON.Identity,
+		Witness:  make([]bls12381.G2Affine, len(rJSON.Witness)),
+	}
+	for i, potPubKey := range rJSON.Witness {
+		path := contribution.IndexPath("witness", i)
+		b, err := contribution.SchemaCheckHex(path, potPubKey, bls12381.SizeOfG2AffineCompressed)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidReceipt, err)
+		}
+		if err := contribution.DecodeG2Point(path, b, &receipt.Witness[i]); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidReceipt, err)
+		}
+	}
+	return receipt, nil
+}
+
+// Signer returns the address of the signer of the receipt, which is signed as an EIP-191 personal message. The
+// signature is hex encoded (optionally 0x prefixed) in the [R || S || V] format, where V can be 0/1 or 27/28.
+func (cr *ContributionReceipt) Signer() (common.Address, error) {
+	signature, err := hex.DecodeString(strings.TrimPrefix(cr.Signature, "0x"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: hex decoding signature: %s", ErrInvalidReceipt, err)
+	}
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("%w: signature length is %d but should be %d", ErrInvalidReceipt, len(signature), crypto.SignatureLength)
+	}
+	if signature[crypto.RecoveryIDOffset] >= 27 {
+		signature[crypto.RecoveryIDOffset] -= 27
+	}
+	pubKey, err := crypto.SigToPub(accounts.TextHash([]byte(cr.Receipt)), signature)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: recovering public key: %s", ErrInvalidReceipt, err)
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// Verify checks that the receipt is signed by the sequencer address, and that its witness has the potPubKeys
+// of the submitted batch contribution. It returns the parsed receipt.
+func (cr *ContributionReceipt) Verify(sequencerAddress string, bc *contribution.BatchContribution) (*Receipt, error) {
+	if !common.IsHexAddress(sequencerAddress) {
+		return nil, fmt.Errorf("invalid sequencer address %s", sequencerAddress)
+	}
+	signer, err := cr.Signer()
+	if err != nil {
+		return nil, err
+	}
+	if signer != common.HexToAddress(sequencerAddress) {
+		return nil, fmt.Errorf("%w: the receipt is signed by %s instead of the sequencer address %s", ErrInvalidReceipt, signer, sequencerAddress)
+	}
+
+	receipt, err := cr.Parse()
+	if err != nil {
+		return nil, err
+	}
+	if len(receipt.Witness) != len(bc.Contributions) {
+		return nil, fmt.Errorf("%w: the receipt witness has %d potPubKeys but %d were submitted", ErrInvalidReceipt, len(receipt.Witness), len(bc.Contributions))
+	}
+	for i := range receipt.Witness {
+		if !receipt.Witness[i].Equal(&bc.Contributions[i].PotPubKey) {
+			return nil, fmt.Errorf("%w: the %d-th potPubKey of the receipt witness doesn't match the submitted one", ErrInvalidReceipt, i)
+		}
+	}
+
+	return receipt, nil
+}
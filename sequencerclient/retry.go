@@ -0,0 +1,87 @@
+package sequencerclient
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy decides if a failed call to the sequencer is retried, see WithRetryPolicy.
+type RetryPolicy interface {
+	// Retry is called after the attempt-th failed attempt of a call with its error. It returns the delay before
+	// the next attempt, or false if the call shouldn't be retried.
+	Retry(attempt int, err error) (time.Duration, bool)
+}
+
+// ExponentialBackoff is a RetryPolicy that retries temporary errors (see IsTemporary) with a delay starting at
+// InitialDelay (one second if not positive) and doubling on each attempt up to MaxDelay (if not zero). A random
+// jitter of up to half the delay is subtracted so many clients don't retry in lockstep. If the sequencer rate limits
+// the calls with a Retry-After, the delay is at least that. MaxAttempts limits the number of retries, or zero for no
+// limit.
+type ExponentialBackoff struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	MaxAttempts  int
+}
+
+// defaultInitialDelay is the InitialDelay if it isn't positive, so a zero value doesn't retry in a hot loop.
+const defaultInitialDelay = time.Second
+
+var jitter = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+func (b *ExponentialBackoff) Retry(attempt int, err error) (time.Duration, bool) {
+	if !IsTemporary(err) || (b.MaxAttempts > 0 && attempt > b.MaxAttempts) {
+		return 0, false
+	}
+
+	delay := b.InitialDelay
+	if delay <= 0 {
+		delay = defaultInitialDelay
+	}
+	for i := 1; i < attempt && delay < math.MaxInt64/2; i++ {
+		delay *= 2
+	}
+	if b.MaxDelay > 0 && delay > b.MaxDelay {
+		delay = b.MaxDelay
+	}
+	if delay/2 > 0 {
+		jitter.Lock()
+		delay -= time.Duration(jitter.Int63n(int64(delay/2) + 1))
+		jitter.Unlock()
+	}
+
+	var rateLimited ErrRateLimited
+	if errors.As(err, &rateLimited) && rateLimited.RetryAfter > delay {
+		delay = rateLimited.RetryAfter
+	}
+
+	return delay, true
+}
+
+// retry runs the call until it succeeds, the retry policy gives up or the context is done. It returns the last
+// error of the call.
+func (c *Client) retry(ctx context.Context, call func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || c.retryPolicy == nil {
+			return err
+		}
+		delay, ok := c.retryPolicy.Retry(attempt, err)
+		if !ok {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
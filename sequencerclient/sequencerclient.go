@@ -5,9 +5,13 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
 
 	"github.com/jsign/go-kzg-ceremony-client/contribution"
 	"github.com/jsign/go-kzg-ceremony-client/transcript"
@@ -15,14 +19,69 @@ import (
 
 type Client struct {
 	sequencerURL string
+	httpClient   *http.Client
+	timeout      time.Duration
+	userAgent    string
+	retryPolicy  RetryPolicy
 }
 
-func New(sequencerURL string) (*Client, error) {
+// New returns a client of the sequencer at sequencerURL. By default, it uses http.DefaultClient without timeouts
+// nor retries, which can be changed with the provided options. All the calls are aborted when their context is
+// done.
+func New(sequencerURL string, opts ...Option) (*Client, error) {
+	o := options{
+		httpClient: http.DefaultClient,
+		userAgent:  DefaultUserAgent,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, fmt.Errorf("applying option: %s", err)
+		}
+	}
+	httpClient, err := o.buildHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Client{
 		sequencerURL: sequencerURL,
+		httpClient:   httpClient,
+		timeout:      o.timeout,
+		userAgent:    o.userAgent,
+		retryPolicy:  o.retryPolicy,
 	}, nil
 }
 
+// newRequest returns a request to the sequencer with the configured timeout, which must be released with the
+// returned cancel function after reading the response.
+func (c *Client) newRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, context.CancelFunc, error) {
+	cancel := func() {}
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.sequencerURL+path, body)
+	if err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("creating request: %s", err)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, cancel, nil
+}
+
+// do sends the request. Network errors are wrapped with ErrSequencerUnavailable, unless ctx is done.
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("making request: %w", err)
+		}
+		return nil, fmt.Errorf("%w: making request: %s", ErrSequencerUnavailable, err)
+	}
+	return res, nil
+}
+
 type CeremonyStatus struct {
 	LobbySize        int    `json:"lobby_size"`
 	NumContributions int    `json:"num_contributions"`
@@ -30,19 +89,29 @@ type CeremonyStatus struct {
 }
 
 func (c *Client) GetStatus(ctx context.Context) (CeremonyStatus, error) {
-	req, err := http.NewRequest("GET", c.sequencerURL+"/info/status", nil)
+	var cs CeremonyStatus
+	err := c.retry(ctx, func() (err error) {
+		cs, err = c.getStatus(ctx)
+		return err
+	})
+	return cs, err
+}
+
+func (c *Client) getStatus(ctx context.Context) (CeremonyStatus, error) {
+	req, cancel, err := c.newRequest(ctx, "GET", "/info/status", nil)
 	if err != nil {
-		return CeremonyStatus{}, fmt.Errorf("creating request: %s", err)
+		return CeremonyStatus{}, err
 	}
+	defer cancel()
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.do(ctx, req)
 	if err != nil {
-		return CeremonyStatus{}, fmt.Errorf("making request: %s", err)
+		return CeremonyStatus{}, err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return CeremonyStatus{}, fmt.Errorf("received status code %d", res.StatusCode)
+		return CeremonyStatus{}, statusError(res)
 	}
 
 	var cs CeremonyStatus
@@ -53,29 +122,44 @@ func (c *Client) GetStatus(ctx context.Context) (CeremonyStatus, error) {
 	return cs, nil
 }
 
+// TryContribute asks the sequencer for our turn to contribute. If it's our turn, it returns the current state to
+// contribute to and true, otherwise we should keep waiting in the lobby and try later.
 func (c *Client) TryContribute(ctx context.Context, sessionID string) (*contribution.BatchContribution, bool, error) {
-	req, err := http.NewRequest("POST", c.sequencerURL+"/lobby/try_contribute", nil)
+	var (
+		bc *contribution.BatchContribution
+		ok bool
+	)
+	err := c.retry(ctx, func() (err error) {
+		bc, ok, err = c.tryContribute(ctx, sessionID)
+		return err
+	})
+	return bc, ok, err
+}
+
+func (c *Client) tryContribute(ctx context.Context, sessionID string) (*contribution.BatchContribution, bool, error) {
+	req, cancel, err := c.newRequest(ctx, "POST", "/lobby/try_contribute", nil)
 	if err != nil {
-		return nil, false, fmt.Errorf("creating request: %s", err)
+		return nil, false, err
 	}
+	defer cancel()
 	req.Header.Add("Authorization", "Bearer "+sessionID)
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.do(ctx, req)
 	if err != nil {
-		return nil, false, fmt.Errorf("making request: %s", err)
+		return nil, false, err
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusUnauthorized {
-		return nil, false, fmt.Errorf("wrong or expired session-id, login again with the sequencer, session-id: %v", sessionID)
+	if res.StatusCode == http.StatusBadRequest {
+		return nil, false, decodeContributionError(res)
 	}
 	if res.StatusCode != http.StatusOK {
-		return nil, false, fmt.Errorf("received status code %d", res.StatusCode)
+		return nil, false, statusError(res)
 	}
 
 	resBody, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, false, fmt.Errorf("reading response: %s", err)
+		return nil, false, fmt.Errorf("%w: reading response: %s", ErrSequencerUnavailable, err)
 	}
 
 	// Check if we're instructed to retry later.
@@ -99,40 +183,63 @@ func (c *Client) TryContribute(ctx context.Context, sessionID string) (*contribu
 	return currentBatch, true, nil
 }
 
+// ContributionReceipt is the receipt returned by the sequencer for an accepted contribution. Receipt is the JSON
+// encoded content, see Parse, and Signature is the sequencer signature of it, see Verify.
 type ContributionReceipt struct {
 	Receipt   string `json:"receipt"`
 	Signature string `json:"signature"`
 }
 
+// Contribute sends the batch contribution to the sequencer. If the batch contribution has an ECDSA signature,
+// it's included in the submission. It's only retried if it failed before sending the contribution, otherwise
+// ErrContributionUnconfirmed is returned.
 func (c *Client) Contribute(ctx context.Context, sessionID string, batch *contribution.BatchContribution) (*ContributionReceipt, error) {
+	var receipt *ContributionReceipt
+	err := c.retry(ctx, func() (err error) {
+		receipt, err = c.contribute(ctx, sessionID, batch)
+		return err
+	})
+	return receipt, err
+}
+
+func (c *Client) contribute(ctx context.Context, sessionID string, batch *contribution.BatchContribution) (*ContributionReceipt, error) {
 	batchJSON, err := contribution.Encode(batch, false)
 	if err != nil {
 		return nil, fmt.Errorf("marshaling batch contribution: %s", err)
 	}
-	req, err := http.NewRequest("POST", c.sequencerURL+"/contribute", bytes.NewReader(batchJSON))
+	req, cancel, err := c.newRequest(ctx, "POST", "/contribute", bytes.NewReader(batchJSON))
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %s", err)
+		return nil, err
 	}
+	defer cancel()
 	req.Header.Add("Authorization", "Bearer "+sessionID)
 	req.Header.Add("Content-Type", "application/json")
 
-	res, err := http.DefaultClient.Do(req)
+	// Once the request was written, the sequencer might have accepted the contribution even if we don't get its
+	// response. Only failures before that are safe to retry.
+	var written atomic.Bool
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) { written.Store(info.Err == nil) },
+	}))
+	res, err := c.do(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("making request: %s", err)
+		if written.Load() && errors.Is(err, ErrSequencerUnavailable) {
+			return nil, fmt.Errorf("%w: %s", ErrContributionUnconfirmed, err)
+		}
+		return nil, err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode == http.StatusBadRequest {
-		var contributionError struct {
-			Code  string `json:"code"`
-			Error string `json:"error"`
-		}
-		_ = json.NewDecoder(res.Body).Decode(&contributionError)
-		return nil, fmt.Errorf("contribution error (Code: %s, Error: %s)", contributionError.Code, contributionError.Error)
+		return nil, decodeContributionError(res)
 	}
 	if res.StatusCode != http.StatusOK {
 		_, _ = io.Copy(io.Discard, res.Body)
-		return nil, fmt.Errorf("unexpected contribution error (status code: %s)", res.Status)
+		err := statusError(res)
+		if errors.Is(err, ErrSequencerUnavailable) {
+			return nil, fmt.Errorf("%w: %s", ErrContributionUnconfirmed, err)
+		}
+		return nil, err
 	}
 
 	var receipt ContributionReceipt
@@ -143,27 +250,83 @@ func (c *Client) Contribute(ctx context.Context, sessionID string, batch *contri
 	return &receipt, nil
 }
 
+// Abort releases our turn to contribute, so the lobby isn't locked until the sequencer compute deadline when
+// we can't send a contribution (e.g: we were interrupted or computing it failed).
+func (c *Client) Abort(ctx context.Context, sessionID string) error {
+	return c.retry(ctx, func() error {
+		return c.abort(ctx, sessionID)
+	})
+}
+
+func (c *Client) abort(ctx context.Context, sessionID string) error {
+	req, cancel, err := c.newRequest(ctx, "POST", "/contribute/abort", nil)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	req.Header.Add("Authorization", "Bearer "+sessionID)
+
+	res, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusBadRequest {
+		return decodeContributionError(res)
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode != http.StatusOK {
+		return statusError(res)
+	}
+
+	return nil
+}
+
 func (c *Client) GetCurrentTranscript(ctx context.Context) (*transcript.BatchTranscript, error) {
-	req, err := http.NewRequest("GET", c.sequencerURL+"/info/current_state", nil)
+	var bt *transcript.BatchTranscript
+	err := c.retry(ctx, func() (err error) {
+		bt, err = c.getCurrentTranscript(ctx)
+		return err
+	})
+	return bt, err
+}
+
+func (c *Client) getCurrentTranscript(ctx context.Context) (*transcript.BatchTranscript, error) {
+	req, cancel, err := c.newRequest(ctx, "GET", "/info/current_state", nil)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %s", err)
+		return nil, err
 	}
+	defer cancel()
 	req.Header.Add("Content-Type", "application/json")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.do(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("making request: %s", err)
+		return nil, err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received status code %d", res.StatusCode)
+		return nil, statusError(res)
 	}
 
 	batchTranscript, err := transcript.Decode(res.Body)
 	if err != nil {
-		return nil, fmt.Errorf("validating current contribution file: %s", err)
+		return nil, fmt.Errorf("decoding current transcript: %w", err)
 	}
 
 	return batchTranscript, nil
 }
+
+// decodeContributionError decodes the error code of a bad request response of the contribution endpoints.
+func decodeContributionError(res *http.Response) error {
+	var errorResponse struct {
+		Code  string `json:"code"`
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&errorResponse)
+	if errorResponse.Code == codeRateLimited {
+		return ErrRateLimited{RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"))}
+	}
+	return &ContributionError{Code: errorResponse.Code, Message: errorResponse.Error}
+}
@@ -2,9 +2,25 @@ package sequencerclient
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/sequencer"
 	"github.com/jsign/go-kzg-ceremony-client/transcript"
 	"github.com/stretchr/testify/require"
 )
@@ -35,8 +51,496 @@ func TestVerifyTranscript(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestContributionReceipt(t *testing.T) {
+	t.Parallel()
+
+	seq, err := sequencer.New(sequencer.Config{
+		Parameters: []contribution.SubCeremonyParameters{{NumG1Powers: 16, NumG2Powers: 5}, {NumG1Powers: 32, NumG2Powers: 5}},
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(seq.Handler())
+	t.Cleanup(server.Close)
+	c, err := New(server.URL)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	sessionID, err := seq.NewSession("git|1|alice")
+	require.NoError(t, err)
+	bc, ok, err := c.TryContribute(ctx, sessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, bc.Contribute(""))
+	contributionReceipt, err := c.Contribute(ctx, sessionID, bc)
+	require.NoError(t, err)
+	status, err := c.GetStatus(ctx)
+	require.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		receipt, err := contributionReceipt.Verify(status.SequencerAddress, bc)
+		require.NoError(t, err)
+		require.Equal(t, "git|1|alice", receipt.Identity)
+		require.Len(t, receipt.Witness, 2)
+
+		// The signature can be 0x prefixed and have a 0/1 recovery id.
+		signature, err := hex.DecodeString(contributionReceipt.Signature)
+		require.NoError(t, err)
+		signature[crypto.RecoveryIDOffset] -= 27
+		other := ContributionReceipt{Receipt: contributionReceipt.Receipt, Signature: "0x" + hex.EncodeToString(signature)}
+		_, err = other.Verify(status.SequencerAddress, bc)
+		require.NoError(t, err)
+	})
+	t.Run("other signer", func(t *testing.T) {
+		t.Parallel()
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		_, err = contributionReceipt.Verify(crypto.PubkeyToAddress(key.PublicKey).Hex(), bc)
+		require.ErrorIs(t, err, ErrInvalidReceipt)
+
+		// A receipt signed by another key.
+		forged := signTestReceipt(t, key, contributionReceipt.Receipt)
+		_, err = forged.Verify(status.SequencerAddress, bc)
+		require.ErrorIs(t, err, ErrInvalidReceipt)
+		require.ErrorContains(t, err, "instead of the sequencer address")
+	})
+	t.Run("tampered receipt", func(t *testing.T) {
+		t.Parallel()
+		tampered := ContributionReceipt{
+			Receipt:   strings.Replace(contributionReceipt.Receipt, "alice", "bob", 1),
+			Signature: contributionReceipt.Signature,
+		}
+		_, err := tampered.Verify(status.SequencerAddress, bc)
+		require.ErrorIs(t, err, ErrInvalidReceipt)
+	})
+	t.Run("other witness", func(t *testing.T) {
+		t.Parallel()
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		otherBC := *bc
+		otherBC.Contributions = []contribution.Contribution{bc.Contributions[1], bc.Contributions[0]}
+		receipt := signTestReceipt(t, key, contributionReceipt.Receipt)
+		_, err = receipt.Verify(crypto.PubkeyToAddress(key.PublicKey).Hex(), &otherBC)
+		require.ErrorIs(t, err, ErrInvalidReceipt)
+		require.ErrorContains(t, err, "the 0-th potPubKey of the receipt witness doesn't match the submitted one")
+
+		receipt = signTestReceipt(t, key, `{"identity":"git|1|alice","witness":["0x1234"]}`)
+		_, err = receipt.Verify(crypto.PubkeyToAddress(key.PublicKey).Hex(), bc)
+		require.ErrorIs(t, err, ErrInvalidReceipt)
+		require.ErrorContains(t, err, "witness[0]: invalid hex")
+	})
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	// The fake sequencer login links redirect straight to the redirect_to URL, as the sequencer does after a
+	// successful GitHub login and a failed Ethereum one.
+	var lobbyFull bool
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/auth/request_link", func(w http.ResponseWriter, r *http.Request) {
+		if lobbyFull {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"code":"AuthErrorPayload::LobbyIsFull","error":"lobby is full"}`))
+			return
+		}
+		redirectTo := url.QueryEscape(r.URL.Query().Get("redirect_to"))
+		_ = json.NewEncoder(w).Encode(AuthLinks{
+			EthAuthURL:    server.URL + "/fake/eth?redirect_to=" + redirectTo,
+			GithubAuthURL: server.URL + "/fake/github?redirect_to=" + redirectTo,
+		})
+	})
+	mux.HandleFunc("/fake/github", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("redirect_to")+"?session_id=504d898c&sub=git|1|alice&nickname=alice&provider=Github", http.StatusFound)
+	})
+	mux.HandleFunc("/fake/eth", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("redirect_to")+"?error=AuthErrorPayload::UserAlreadyContributed&message=already+contributed", http.StatusFound)
+	})
+	c, err := New(server.URL)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	browse := func(link func(AuthLinks) string) func(AuthLinks) error {
+		return func(links AuthLinks) error {
+			res, err := http.Get(link(links))
+			if err != nil {
+				return err
+			}
+			return res.Body.Close()
+		}
+	}
+
+	session, err := c.Login(ctx, "127.0.0.1:0", browse(func(links AuthLinks) string { return links.GithubAuthURL }))
+	require.NoError(t, err)
+	require.Equal(t, &Session{SessionID: "504d898c", Subject: "git|1|alice", Nickname: "alice", Provider: "Github"}, session)
+
+	// Callbacks without the state of the login are rejected, and the login keeps waiting for the right one.
+	session, err = c.Login(ctx, "127.0.0.1:0", func(links AuthLinks) error {
+		githubURL, err := url.Parse(links.GithubAuthURL)
+		require.NoError(t, err)
+		callbackURL, err := url.Parse(githubURL.Query().Get("redirect_to"))
+		require.NoError(t, err)
+		for _, path := range []string{"/callback/", "/callback/" + strings.Repeat("0", 32), "/callback"} {
+			res, err := http.Get("http://" + callbackURL.Host + path + "?session_id=forged")
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			require.NotEqual(t, http.StatusOK, res.StatusCode, path)
+		}
+		return browse(func(links AuthLinks) string { return links.GithubAuthURL })(links)
+	})
+	require.NoError(t, err)
+	require.Equal(t, "504d898c", session.SessionID)
+
+	_, err = c.Login(ctx, "127.0.0.1:0", browse(func(links AuthLinks) string { return links.EthAuthURL }))
+	var authErr *AuthError
+	require.ErrorAs(t, err, &authErr)
+	require.Equal(t, "AuthErrorPayload::UserAlreadyContributed", authErr.Code)
+	require.EqualError(t, err, "you can only contribute once per GitHub account or Ethereum address (AuthErrorPayload::UserAlreadyContributed)")
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = c.Login(timeoutCtx, "127.0.0.1:0", func(AuthLinks) error { return nil })
+	require.EqualError(t, err, "timed out waiting for the login to finish")
+
+	lobbyFull = true
+	_, err = c.Login(ctx, "127.0.0.1:0", func(AuthLinks) error { return nil })
+	require.ErrorAs(t, err, &authErr)
+	require.Equal(t, "AuthErrorPayload::LobbyIsFull", authErr.Code)
+}
+
+func TestParseAuthCallback(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query   string
+		session *Session
+		err     *AuthError
+	}{
+		{query: "session_id=abc", session: &Session{SessionID: "abc"}},
+		{query: "code=AuthErrorPayload::InvalidAuthCode&message=stale", err: &AuthError{Code: "AuthErrorPayload::InvalidAuthCode", Message: "stale"}},
+		{query: "error=AuthErrorPayload::UserCreatedAfterDeadline", err: &AuthError{Code: "AuthErrorPayload::UserCreatedAfterDeadline"}},
+		{query: "error=unexpected", err: &AuthError{Message: "unexpected"}},
+		{query: "", err: &AuthError{Message: "the sequencer redirect doesn't have a session id"}},
+	}
+	for _, test := range tests {
+		query, err := url.ParseQuery(test.query)
+		require.NoError(t, err)
+		session, err := ParseAuthCallback(query)
+		if test.err != nil {
+			require.Equal(t, test.err, err, test.query)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, test.session, session)
+	}
+}
+
+// createClient returns a client of a local sequencer that already has a contribution.
+func TestClientOptions(t *testing.T) {
+	t.Parallel()
+
+	statusJSON := `{"lobby_size":1,"num_contributions":2,"sequencer_address":"0x5e30"}`
+	userAgents := make(chan string, 1)
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/info/status", func(w http.ResponseWriter, r *http.Request) {
+		userAgents <- r.Header.Get("User-Agent")
+		_, _ = w.Write([]byte(statusJSON))
+	})
+	mux.HandleFunc("/info/current_state", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	ctx := context.Background()
+
+	t.Run("user agent", func(t *testing.T) {
+		c, err := New(server.URL, WithHTTPClient(server.Client()))
+		require.NoError(t, err)
+		_, err = c.GetStatus(ctx)
+		require.NoError(t, err)
+		require.Equal(t, DefaultUserAgent, <-userAgents)
+
+		c, err = New(server.URL, WithHTTPClient(server.Client()), WithUserAgent("kzgcli-test"))
+		require.NoError(t, err)
+		_, err = c.GetStatus(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "kzgcli-test", <-userAgents)
+	})
+
+	t.Run("timeout and cancellation", func(t *testing.T) {
+		c, err := New(server.URL, WithHTTPClient(server.Client()), WithTimeout(100*time.Millisecond))
+		require.NoError(t, err)
+		_, err = c.GetCurrentTranscript(ctx)
+		require.ErrorContains(t, err, "context deadline exceeded")
+
+		c, err = New(server.URL, WithHTTPClient(server.Client()))
+		require.NoError(t, err)
+		cancelCtx, cancel := context.WithCancel(ctx)
+		time.AfterFunc(100*time.Millisecond, cancel)
+		_, err = c.GetCurrentTranscript(cancelCtx)
+		require.ErrorContains(t, err, "context canceled")
+
+		_, err = New(server.URL, WithTimeout(-time.Second))
+		require.Error(t, err)
+	})
+
+	t.Run("ca certificates and pinning", func(t *testing.T) {
+		cert := server.Certificate()
+		certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+
+		// The test server certificate isn't trusted by default.
+		c, err := New(server.URL)
+		require.NoError(t, err)
+		_, err = c.GetStatus(ctx)
+		require.ErrorContains(t, err, "certificate")
+
+		c, err = New(server.URL, WithCACertificates(certPEM))
+		require.NoError(t, err)
+		_, err = c.GetStatus(ctx)
+		require.NoError(t, err)
+		<-userAgents
+
+		c, err = New(server.URL, WithCACertificates(certPEM), WithPinnedSPKIs(sha256.Sum256(cert.RawSubjectPublicKeyInfo)))
+		require.NoError(t, err)
+		_, err = c.GetStatus(ctx)
+		require.NoError(t, err)
+		<-userAgents
+
+		c, err = New(server.URL, WithCACertificates(certPEM), WithPinnedSPKIs(sha256.Sum256([]byte("other key"))))
+		require.NoError(t, err)
+		_, err = c.GetStatus(ctx)
+		require.ErrorContains(t, err, "don't match any pinned public key")
+
+		_, err = New(server.URL, WithCACertificates([]byte("not a certificate")))
+		require.Error(t, err)
+
+		// TLS options need an *http.Transport to configure.
+		_, err = New(server.URL, WithHTTPClient(&http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}), WithCACertificates(certPEM))
+		require.Error(t, err)
+	})
+}
+
+func TestSequencerErrors(t *testing.T) {
+	t.Parallel()
+
+	var statusCode int
+	var header http.Header
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	c, err := New(server.URL)
+	require.NoError(t, err)
+	ctx := context.Background()
+	bc := &contribution.BatchContribution{}
+
+	statusCode, body = http.StatusUnauthorized, `{"error":"unknown session id"}`
+	_, _, err = c.TryContribute(ctx, "session")
+	require.ErrorIs(t, err, ErrUnauthorized)
+	require.False(t, IsTemporary(err))
+	_, err = c.Contribute(ctx, "session", bc)
+	require.ErrorIs(t, err, ErrUnauthorized)
+
+	statusCode, body = http.StatusBadRequest, `{"code":"ContributeError::InvalidContribution","error":"invalid potPubKey"}`
+	_, err = c.Contribute(ctx, "session", bc)
+	var contributionErr *ContributionError
+	require.ErrorAs(t, err, &contributionErr)
+	require.Equal(t, &ContributionError{Code: "ContributeError::InvalidContribution", Message: "invalid potPubKey"}, contributionErr)
+	require.False(t, IsTemporary(err))
+	statusCode, body = http.StatusBadRequest, `{"code":"TryContributeError::AlreadyContributed","error":"already contributed"}`
+	_, _, err = c.TryContribute(ctx, "session")
+	require.ErrorAs(t, err, &contributionErr)
+	require.Equal(t, "TryContributeError::AlreadyContributed", contributionErr.Code)
+
+	statusCode, header, body = http.StatusTooManyRequests, http.Header{"Retry-After": {"7"}}, ""
+	_, _, err = c.TryContribute(ctx, "session")
+	var rateLimited ErrRateLimited
+	require.ErrorAs(t, err, &rateLimited)
+	require.Equal(t, 7*time.Second, rateLimited.RetryAfter)
+	require.True(t, IsTemporary(err))
+	statusCode, header, body = http.StatusBadRequest, nil, `{"code":"TryContributeError::RateLimited","error":"rate limited"}`
+	_, _, err = c.TryContribute(ctx, "session")
+	require.Equal(t, ErrRateLimited{}, err)
+
+	statusCode, body = http.StatusServiceUnavailable, ""
+	_, err = c.GetStatus(ctx)
+	require.ErrorIs(t, err, ErrSequencerUnavailable)
+	require.True(t, IsTemporary(err))
+	_, err = c.GetCurrentTranscript(ctx)
+	require.ErrorIs(t, err, ErrSequencerUnavailable)
+	_, err = c.Contribute(ctx, "session", bc)
+	require.ErrorIs(t, err, ErrContributionUnconfirmed)
+	require.False(t, IsTemporary(err))
+
+	closedServer := httptest.NewServer(http.NotFoundHandler())
+	closedServer.Close()
+	c, err = New(closedServer.URL)
+	require.NoError(t, err)
+	_, err = c.GetStatus(ctx)
+	require.ErrorIs(t, err, ErrSequencerUnavailable)
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.GetStatus(cancelCtx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.False(t, IsTemporary(err))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("exponential backoff", func(t *testing.T) {
+		b := &ExponentialBackoff{InitialDelay: time.Second, MaxDelay: 10 * time.Second, MaxAttempts: 6}
+		unavailable := fmt.Errorf("%w: test", ErrSequencerUnavailable)
+		for attempt, maxDelay := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+			delay, ok := b.Retry(attempt+1, unavailable)
+			require.True(t, ok)
+			require.GreaterOrEqual(t, delay, maxDelay/2)
+			require.LessOrEqual(t, delay, maxDelay)
+		}
+		_, ok := b.Retry(7, unavailable)
+		require.False(t, ok)
+
+		delay, ok := b.Retry(1, ErrRateLimited{RetryAfter: time.Minute})
+		require.True(t, ok)
+		require.Equal(t, time.Minute, delay)
+
+		_, ok = b.Retry(1, ErrUnauthorized)
+		require.False(t, ok)
+		_, ok = b.Retry(1, &ContributionError{Code: "ContributeError::NotUsersTurn"})
+		require.False(t, ok)
+
+		// Without limits, the delay doesn't overflow.
+		b = &ExponentialBackoff{InitialDelay: time.Second}
+		delay, ok = b.Retry(1000, unavailable)
+		require.True(t, ok)
+		require.Greater(t, delay, time.Duration(0))
+
+		// A zero or negative initial delay doesn't retry in a hot loop.
+		for _, initialDelay := range []time.Duration{0, -time.Second} {
+			b = &ExponentialBackoff{InitialDelay: initialDelay}
+			delay, ok = b.Retry(1, unavailable)
+			require.True(t, ok)
+			require.GreaterOrEqual(t, delay, defaultInitialDelay/2)
+			require.LessOrEqual(t, delay, defaultInitialDelay)
+		}
+	})
+
+	t.Run("client", func(t *testing.T) {
+		var calls int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			switch calls {
+			case 1:
+				w.WriteHeader(http.StatusBadGateway)
+			case 2:
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				_, _ = w.Write([]byte(`{"lobby_size":1,"num_contributions":2,"sequencer_address":"0x5e30"}`))
+			}
+		}))
+		t.Cleanup(server.Close)
+		ctx := context.Background()
+
+		c, err := New(server.URL, WithRetryPolicy(&ExponentialBackoff{InitialDelay: time.Millisecond}))
+		require.NoError(t, err)
+		status, err := c.GetStatus(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, status.NumContributions)
+		require.Equal(t, 3, calls)
+
+		calls = 0
+		c, err = New(server.URL, WithRetryPolicy(&ExponentialBackoff{InitialDelay: time.Millisecond, MaxAttempts: 1}))
+		require.NoError(t, err)
+		_, err = c.GetStatus(ctx)
+		var rateLimited ErrRateLimited
+		require.ErrorAs(t, err, &rateLimited)
+		require.Equal(t, 2, calls)
+
+		// A done context stops the retries.
+		calls = 0
+		c, err = New(server.URL, WithRetryPolicy(&ExponentialBackoff{InitialDelay: time.Hour}))
+		require.NoError(t, err)
+		timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err = c.GetStatus(timeoutCtx)
+		require.ErrorIs(t, err, ErrSequencerUnavailable)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("contribute", func(t *testing.T) {
+		// The connection is closed after reading the contribution, so the response is lost.
+		var calls int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			_, _ = io.Copy(io.Discard, r.Body)
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+		}))
+		t.Cleanup(server.Close)
+		retryPolicy := WithRetryPolicy(&ExponentialBackoff{InitialDelay: time.Millisecond, MaxAttempts: 2})
+		bc := &contribution.BatchContribution{}
+
+		c, err := New(server.URL, retryPolicy)
+		require.NoError(t, err)
+		_, err = c.Contribute(context.Background(), "session", bc)
+		require.ErrorIs(t, err, ErrContributionUnconfirmed)
+		require.Equal(t, 1, calls)
+
+		// If the contribution wasn't sent, it's retried.
+		closedServer := httptest.NewServer(http.NotFoundHandler())
+		closedServer.Close()
+		c, err = New(closedServer.URL, retryPolicy)
+		require.NoError(t, err)
+		_, err = c.Contribute(context.Background(), "session", bc)
+		require.ErrorIs(t, err, ErrSequencerUnavailable)
+		require.NotErrorIs(t, err, ErrContributionUnconfirmed)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
 func createClient(t *testing.T) *Client {
-	c, err := New("https://seq.ceremony.ethereum.org")
+	seq, err := sequencer.New(sequencer.Config{
+		Parameters: []contribution.SubCeremonyParameters{{NumG1Powers: 16, NumG2Powers: 5}},
+	})
 	require.NoError(t, err)
+	server := httptest.NewServer(seq.Handler())
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL)
+	require.NoError(t, err)
+
+	sessionID, err := seq.NewSession("git|1|alice")
+	require.NoError(t, err)
+	bc, ok, err := c.TryContribute(context.Background(), sessionID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, bc.Contribute(""))
+	_, err = c.Contribute(context.Background(), sessionID, bc)
+	require.NoError(t, err)
+
 	return c
 }
+
+func signTestReceipt(t *testing.T, key *ecdsa.PrivateKey, receipt string) *ContributionReceipt {
+	signature, err := crypto.Sign(accounts.TextHash([]byte(receipt)), key)
+	require.NoError(t, err)
+	return &ContributionReceipt{Receipt: receipt, Signature: hex.EncodeToString(signature)}
+}
@@ -0,0 +1,84 @@
+package transcript
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+)
+
+// Signatures are the signatures of a participant recorded in the transcript together with its contribution.
+type Signatures struct {
+	// ECDSA is the EIP-712 signature of the potPubKeys by the participant Ethereum address, if any.
+	ECDSA []byte
+	// BLS are the signatures of the participant identity with each sub-ceremony secret. If not nil, it must
+	// have one (possibly nil) signature per sub-ceremony.
+	BLS []*bls12381.G1Affine
+}
+
+// Apply extends the transcript with a contribution. The contribution is verified against the current powers,
+// which are replaced by the contributed ones, and the new running product and potPubKey are appended to the
+// witness of each sub-ceremony. The participant id and the optional signatures are recorded in the transcript.
+// Signatures aren't verified, so the caller should only provide the ones it wants to keep.
+// If the contribution is invalid, an error is returned and the transcript isn't modified.
+func (bt *BatchTranscript) Apply(participantID string, bc *contribution.BatchContribution, sigs ...Signatures) error {
+	var signatures Signatures
+	switch len(sigs) {
+	case 0:
+	case 1:
+		signatures = sigs[0]
+	default:
+		return fmt.Errorf("at most one set of signatures can be provided, got %d", len(sigs))
+	}
+	if signatures.BLS != nil && len(signatures.BLS) != len(bt.Transcripts) {
+		return fmt.Errorf("there're %d bls signatures but %d sub-ceremonies", len(signatures.BLS), len(bt.Transcripts))
+	}
+	if len(bt.ParticipantIDs) == 0 {
+		return fmt.Errorf("the transcript doesn't have participants")
+	}
+	if len(bt.ParticipantECDSASignatures) != len(bt.ParticipantIDs) {
+		return fmt.Errorf("there're %d ecdsa signatures but %d participant ids", len(bt.ParticipantECDSASignatures), len(bt.ParticipantIDs))
+	}
+	for i, transcript := range bt.Transcripts {
+		witness := transcript.Witness
+		if len(witness.RunningProducts) != len(bt.ParticipantIDs) || len(witness.PotPubKeys) != len(bt.ParticipantIDs) || len(witness.BLSSignatures) != len(bt.ParticipantIDs) {
+			return fmt.Errorf("%d-th transcript witness has %d running products, %d potPubKeys and %d bls signatures but there're %d participant ids", i, len(witness.RunningProducts), len(witness.PotPubKeys), len(witness.BLSSignatures), len(bt.ParticipantIDs))
+		}
+	}
+
+	ok, err := bc.Verify(bt.CurrentBatchContribution())
+	if err != nil {
+		return fmt.Errorf("verifying contribution: %s", err)
+	}
+	if !ok {
+		return fmt.Errorf("contribution verification failed")
+	}
+
+	for i := range bt.Transcripts {
+		transcript := &bt.Transcripts[i]
+		c := bc.Contributions[i]
+
+		// The powers are copied, so later changes of the contribution don't modify the transcript.
+		transcript.PowersOfTau = contribution.PowersOfTau{
+			G1Affines: append([]bls12381.G1Affine(nil), c.PowersOfTau.G1Affines...),
+			G2Affines: append([]bls12381.G2Affine(nil), c.PowersOfTau.G2Affines...),
+		}
+		transcript.Witness.RunningProducts = append(transcript.Witness.RunningProducts, c.PowersOfTau.G1Affines[1])
+		transcript.Witness.PotPubKeys = append(transcript.Witness.PotPubKeys, c.PotPubKey)
+		var blsSignature *bls12381.G1Affine
+		if signatures.BLS != nil && signatures.BLS[i] != nil {
+			signature := *signatures.BLS[i]
+			blsSignature = &signature
+		}
+		transcript.Witness.BLSSignatures = append(transcript.Witness.BLSSignatures, blsSignature)
+	}
+	bt.ParticipantIDs = append(bt.ParticipantIDs, participantID)
+	var ecdsaSignature string
+	if signatures.ECDSA != nil {
+		ecdsaSignature = "0x" + hex.EncodeToString(signatures.ECDSA)
+	}
+	bt.ParticipantECDSASignatures = append(bt.ParticipantECDSASignatures, ecdsaSignature)
+
+	return nil
+}
@@ -0,0 +1,298 @@
+package transcript
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"runtime"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"golang.org/x/sync/errgroup"
+)
+
+// The binary container stores a batch transcript with the same header, flags and checksum as the batch
+// contribution one (see contribution.EncodeBinary), but its own magic:
+//
+//	magic "KZGT" | version (1 byte) | flags (1 byte) | numTranscripts (uint32) | numParticipants (uint32)
+//	numParticipants * [participant id length (uint32) | participant id | ECDSA signature length (uint32) | ECDSA signature]
+//	numTranscripts * [numG1Powers (uint32) | numG2Powers (uint32) | numParticipants * BLS signature flag (1 byte)]
+//	numTranscripts * [G1 powers | G2 powers | numParticipants * [running product | potPubKey | BLS signature (if flagged)]]
+//	SHA-256 checksum of all the previous bytes
+//
+// Integers are big-endian, participant ids and ECDSA signatures are stored as in the JSON format, and points
+// are stored back to back compressed (or uncompressed if flagged). Unknown JSON fields aren't stored.
+const (
+	binaryMagic   = "KZGT"
+	binaryVersion = 1
+
+	binaryFlagUncompressed = 1 << 0
+
+	binaryHeaderSize = len(binaryMagic) + 1 + 1 + 4 + 4
+)
+
+// binaryPointsChunkSize is the number of powers or participants decoded by each goroutine.
+const binaryPointsChunkSize = 1024
+
+// IsBinary returns true if the bytes look like a binary transcript container instead of JSON.
+func IsBinary(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(binaryMagic))
+}
+
+// EncodeBinary encodes the batch transcript in the binary container format. If uncompressed is true, points
+// are stored uncompressed which doubles the size but saves the decompression work when decoding.
+func EncodeBinary(bt *BatchTranscript, uncompressed bool) ([]byte, error) {
+	numParticipants := len(bt.ParticipantIDs)
+	if len(bt.ParticipantECDSASignatures) != numParticipants {
+		return nil, fmt.Errorf("there are %d participant ids but %d ECDSA signatures", numParticipants, len(bt.ParticipantECDSASignatures))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(binaryMagic)
+	buf.WriteByte(binaryVersion)
+	var flags byte
+	if uncompressed {
+		flags |= binaryFlagUncompressed
+	}
+	buf.WriteByte(flags)
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(bt.Transcripts)))
+	_ = binary.Write(&buf, binary.BigEndian, uint32(numParticipants))
+	for i := range bt.ParticipantIDs {
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(bt.ParticipantIDs[i])))
+		buf.WriteString(bt.ParticipantIDs[i])
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(bt.ParticipantECDSASignatures[i])))
+		buf.WriteString(bt.ParticipantECDSASignatures[i])
+	}
+	for i, t := range bt.Transcripts {
+		if t.NumG1Powers != len(t.PowersOfTau.G1Affines) || t.NumG2Powers != len(t.PowersOfTau.G2Affines) {
+			return nil, fmt.Errorf("the number of powers of the %d-th transcript doesn't match the declared %d G1 and %d G2 powers", i, t.NumG1Powers, t.NumG2Powers)
+		}
+		if len(t.Witness.RunningProducts) != numParticipants || len(t.Witness.PotPubKeys) != numParticipants || len(t.Witness.BLSSignatures) != numParticipants {
+			return nil, fmt.Errorf("the witness of the %d-th transcript doesn't have an entry for each of the %d participants", i, numParticipants)
+		}
+		_ = binary.Write(&buf, binary.BigEndian, uint32(t.NumG1Powers))
+		_ = binary.Write(&buf, binary.BigEndian, uint32(t.NumG2Powers))
+		for _, blsSignature := range t.Witness.BLSSignatures {
+			if blsSignature != nil {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+		}
+	}
+
+	writeG1 := func(p *bls12381.G1Affine) {
+		if uncompressed {
+			pBytes := p.RawBytes()
+			buf.Write(pBytes[:])
+			return
+		}
+		pBytes := p.Bytes()
+		buf.Write(pBytes[:])
+	}
+	writeG2 := func(p *bls12381.G2Affine) {
+		if uncompressed {
+			pBytes := p.RawBytes()
+			buf.Write(pBytes[:])
+			return
+		}
+		pBytes := p.Bytes()
+		buf.Write(pBytes[:])
+	}
+	for i := range bt.Transcripts {
+		t := &bt.Transcripts[i]
+		for j := range t.PowersOfTau.G1Affines {
+			writeG1(&t.PowersOfTau.G1Affines[j])
+		}
+		for j := range t.PowersOfTau.G2Affines {
+			writeG2(&t.PowersOfTau.G2Affines[j])
+		}
+		for j := range t.Witness.RunningProducts {
+			writeG1(&t.Witness.RunningProducts[j])
+			writeG2(&t.Witness.PotPubKeys[j])
+			if t.Witness.BLSSignatures[j] != nil {
+				writeG1(t.Witness.BLSSignatures[j])
+			}
+		}
+	}
+
+	checksum := sha256.Sum256(buf.Bytes())
+	buf.Write(checksum[:])
+
+	return buf.Bytes(), nil
+}
+
+// DecodeBinary decodes a batch transcript from the binary container format. As in the JSON format, all the
+// points are subgroup checked.
+func DecodeBinary(b []byte) (*BatchTranscript, error) {
+	if !IsBinary(b) {
+		return nil, fmt.Errorf("missing binary container magic bytes")
+	}
+	if len(b) < binaryHeaderSize+sha256.Size {
+		return nil, fmt.Errorf("the binary container is too short (%d bytes)", len(b))
+	}
+	content, checksum := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+	if expected := sha256.Sum256(content); !bytes.Equal(checksum, expected[:]) {
+		return nil, fmt.Errorf("checksum mismatch, the file is corrupted")
+	}
+
+	version, flags := content[len(binaryMagic)], content[len(binaryMagic)+1]
+	if version != binaryVersion {
+		return nil, fmt.Errorf("unsupported binary container version %d", version)
+	}
+	if flags&^binaryFlagUncompressed != 0 {
+		return nil, fmt.Errorf("unknown flags %08b", flags)
+	}
+	g1Size, g2Size := bls12381.SizeOfG1AffineCompressed, bls12381.SizeOfG2AffineCompressed
+	if flags&binaryFlagUncompressed != 0 {
+		g1Size, g2Size = bls12381.SizeOfG1AffineUncompressed, bls12381.SizeOfG2AffineUncompressed
+	}
+
+	numTranscripts := uint64(binary.BigEndian.Uint32(content[len(binaryMagic)+2:]))
+	numParticipants := uint64(binary.BigEndian.Uint32(content[len(binaryMagic)+6:]))
+	offset := uint64(binaryHeaderSize)
+
+	// Check the sizes in the headers match the content before allocating anything.
+	if uint64(len(content)) < offset+numParticipants*8 {
+		return nil, fmt.Errorf("the binary container is too short for %d participants", numParticipants)
+	}
+	readString := func() (string, error) {
+		if uint64(len(content)) < offset+4 {
+			return "", fmt.Errorf("the binary container is too short for the participants")
+		}
+		length := uint64(binary.BigEndian.Uint32(content[offset:]))
+		offset += 4
+		if uint64(len(content)) < offset+length {
+			return "", fmt.Errorf("the binary container is too short for the participants")
+		}
+		s := string(content[offset : offset+length])
+		offset += length
+		return s, nil
+	}
+	bt := BatchTranscript{
+		ParticipantIDs:             make([]string, numParticipants),
+		ParticipantECDSASignatures: make([]string, numParticipants),
+	}
+	for i := range bt.ParticipantIDs {
+		var err error
+		if bt.ParticipantIDs[i], err = readString(); err != nil {
+			return nil, err
+		}
+		if bt.ParticipantECDSASignatures[i], err = readString(); err != nil {
+			return nil, err
+		}
+	}
+	if numTranscripts > (uint64(len(content))-offset)/(8+numParticipants) {
+		return nil, fmt.Errorf("the binary container is too short for %d transcripts of %d participants", numTranscripts, numParticipants)
+	}
+	bt.Transcripts = make([]Transcript, numTranscripts)
+	bodySize := uint64(0)
+	for i := range bt.Transcripts {
+		t := &bt.Transcripts[i]
+		numG1Powers := uint64(binary.BigEndian.Uint32(content[offset:]))
+		numG2Powers := uint64(binary.BigEndian.Uint32(content[offset+4:]))
+		offset += 8
+		t.NumG1Powers = int(numG1Powers)
+		t.NumG2Powers = int(numG2Powers)
+		bodySize += numG1Powers*uint64(g1Size) + numG2Powers*uint64(g2Size) + numParticipants*uint64(g1Size+g2Size)
+
+		t.Witness.BLSSignatures = make([]*bls12381.G1Affine, numParticipants)
+		for j, blsFlag := range content[offset : offset+numParticipants] {
+			switch blsFlag {
+			case 0:
+			case 1:
+				t.Witness.BLSSignatures[j] = &bls12381.G1Affine{}
+				bodySize += uint64(g1Size)
+			default:
+				return nil, fmt.Errorf("unknown BLS signature flag %d of the %d-th participant in the %d-th transcript", blsFlag, j, i)
+			}
+		}
+		offset += numParticipants
+		if bodySize > uint64(len(content)) {
+			return nil, fmt.Errorf("the binary container is too short for the %d-th transcript", i)
+		}
+	}
+	if uint64(len(content)) != offset+bodySize {
+		return nil, fmt.Errorf("the binary container has %d bytes but the header describes %d", len(content), offset+bodySize)
+	}
+
+	var group errgroup.Group
+	group.SetLimit(runtime.NumCPU())
+	for i := range bt.Transcripts {
+		t := &bt.Transcripts[i]
+		path := contribution.IndexPath("transcripts", i)
+		t.PowersOfTau.G1Affines = make([]bls12381.G1Affine, t.NumG1Powers)
+		t.PowersOfTau.G2Affines = make([]bls12381.G2Affine, t.NumG2Powers)
+		t.Witness.RunningProducts = make([]bls12381.G1Affine, numParticipants)
+		t.Witness.PotPubKeys = make([]bls12381.G2Affine, numParticipants)
+
+		g1Powers := content[offset : offset+uint64(t.NumG1Powers*g1Size)]
+		offset += uint64(len(g1Powers))
+		for start := 0; start < t.NumG1Powers; start += binaryPointsChunkSize {
+			start := start
+			group.Go(func() error {
+				for k := start; k < start+binaryPointsChunkSize && k < t.NumG1Powers; k++ {
+					pointPath := contribution.IndexPath(contribution.JoinPath(path, "powersOfTau.G1Powers"), k)
+					if err := contribution.DecodeG1Point(pointPath, g1Powers[k*g1Size:(k+1)*g1Size], &t.PowersOfTau.G1Affines[k]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+
+		g2Powers := content[offset : offset+uint64(t.NumG2Powers*g2Size)]
+		offset += uint64(len(g2Powers))
+		group.Go(func() error {
+			for k := range t.PowersOfTau.G2Affines {
+				pointPath := contribution.IndexPath(contribution.JoinPath(path, "powersOfTau.G2Powers"), k)
+				if err := contribution.DecodeG2Point(pointPath, g2Powers[k*g2Size:(k+1)*g2Size], &t.PowersOfTau.G2Affines[k]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		// The witness entries have a variable size depending on the BLS signature, so the offset of each chunk of
+		// participants is computed before decoding it.
+		for start := 0; start < int(numParticipants); start += binaryPointsChunkSize {
+			start, chunkOffset := start, offset
+			end := start + binaryPointsChunkSize
+			if end > int(numParticipants) {
+				end = int(numParticipants)
+			}
+			for k := start; k < end; k++ {
+				offset += uint64(g1Size + g2Size)
+				if t.Witness.BLSSignatures[k] != nil {
+					offset += uint64(g1Size)
+				}
+			}
+			group.Go(func() error {
+				witness := content[chunkOffset:]
+				for k := start; k < end; k++ {
+					if err := contribution.DecodeG1Point(contribution.IndexPath(contribution.JoinPath(path, "witness.runningProducts"), k), witness[:g1Size], &t.Witness.RunningProducts[k]); err != nil {
+						return err
+					}
+					witness = witness[g1Size:]
+					if err := contribution.DecodeG2Point(contribution.IndexPath(contribution.JoinPath(path, "witness.potPubkeys"), k), witness[:g2Size], &t.Witness.PotPubKeys[k]); err != nil {
+						return err
+					}
+					witness = witness[g2Size:]
+					if t.Witness.BLSSignatures[k] != nil {
+						if err := contribution.DecodeG1Point(contribution.IndexPath(contribution.JoinPath(path, "witness.blsSignatures"), k), witness[:g1Size], t.Witness.BLSSignatures[k]); err != nil {
+							return err
+						}
+						witness = witness[g1Size:]
+					}
+				}
+				return nil
+			})
+		}
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	return &bt, nil
+}
@@ -0,0 +1,125 @@
+package transcript
+
+import (
+	"fmt"
+	"runtime"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"golang.org/x/sync/errgroup"
+)
+
+// SignatureStatus is the result of verifying an optional participant signature.
+type SignatureStatus int
+
+const (
+	SignatureAbsent SignatureStatus = iota
+	SignatureValid
+	SignatureInvalid
+)
+
+func (ss SignatureStatus) String() string {
+	switch ss {
+	case SignatureAbsent:
+		return "absent"
+	case SignatureValid:
+		return "valid"
+	case SignatureInvalid:
+		return "invalid"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(ss))
+	}
+}
+
+// BLSSignatureResult is the result of verifying the BLS signatures of a participant.
+type BLSSignatureResult struct {
+	ParticipantID string
+	// Status is valid only if the signatures of all sub-ceremonies are valid, and absent only if
+	// all of them are absent. Any other case is considered invalid.
+	Status SignatureStatus
+	// SubCeremonies contains the status of the signature in each sub-ceremony.
+	SubCeremonies []SignatureStatus
+}
+
+// VerifyBLSSignatures checks every non-empty BLS signature against the participant identity and the potPubKey
+// of the corresponding sub-ceremony, i.e: e(signature, g2) == e(H(participantID), potPubKey).
+func (bt *BatchTranscript) VerifyBLSSignatures() ([]BLSSignatureResult, error) {
+	for i, transcript := range bt.Transcripts {
+		if len(transcript.Witness.PotPubKeys) != len(bt.ParticipantIDs) {
+			return nil, fmt.Errorf("%d-th transcript has %d potPubKeys but there're %d participant ids", i, len(transcript.Witness.PotPubKeys), len(bt.ParticipantIDs))
+		}
+		if len(transcript.Witness.BLSSignatures) != len(bt.ParticipantIDs) {
+			return nil, fmt.Errorf("%d-th transcript has %d bls signatures but there're %d participant ids", i, len(transcript.Witness.BLSSignatures), len(bt.ParticipantIDs))
+		}
+	}
+
+	results := make([]BLSSignatureResult, len(bt.ParticipantIDs))
+	var g errgroup.Group
+	g.SetLimit(runtime.NumCPU())
+	for j := range bt.ParticipantIDs {
+		j := j
+		g.Go(func() error {
+			results[j] = BLSSignatureResult{
+				ParticipantID: bt.ParticipantIDs[j],
+				SubCeremonies: make([]SignatureStatus, len(bt.Transcripts)),
+			}
+
+			var hashedIdentity *bls12381.G1Affine
+			for i := range bt.Transcripts {
+				signature := bt.Transcripts[i].Witness.BLSSignatures[j]
+				if signature == nil {
+					results[j].SubCeremonies[i] = SignatureAbsent
+					continue
+				}
+				if hashedIdentity == nil {
+					h, err := contribution.HashIdentity(bt.ParticipantIDs[j])
+					if err != nil {
+						return fmt.Errorf("hashing %d-th participant identity: %s", j, err)
+					}
+					hashedIdentity = &h
+				}
+
+				var negPotPubKey bls12381.G2Affine
+				negPotPubKey.Neg(&bt.Transcripts[i].Witness.PotPubKeys[j])
+				ok, err := bls12381.PairingCheck(
+					[]bls12381.G1Affine{*signature, *hashedIdentity},
+					[]bls12381.G2Affine{g2Generator, negPotPubKey})
+				if err != nil {
+					return fmt.Errorf("pairing check of %d-th participant signature: %s", j, err)
+				}
+				results[j].SubCeremonies[i] = SignatureInvalid
+				if ok {
+					results[j].SubCeremonies[i] = SignatureValid
+				}
+			}
+			results[j].Status = aggregateSignatureStatus(results[j].SubCeremonies)
+
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("verifying bls signatures: %s", err)
+	}
+
+	return results, nil
+}
+
+func aggregateSignatureStatus(statuses []SignatureStatus) SignatureStatus {
+	var numValid, numAbsent int
+	for _, status := range statuses {
+		switch status {
+		case SignatureValid:
+			numValid++
+		case SignatureAbsent:
+			numAbsent++
+		}
+	}
+	switch {
+	case numAbsent == len(statuses):
+		return SignatureAbsent
+	case numValid == len(statuses):
+		return SignatureValid
+	default:
+		return SignatureInvalid
+	}
+}
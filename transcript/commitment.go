@@ -0,0 +1,220 @@
+package transcript
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+)
+
+// The transcript commitment is:
+//
+//	SHA-256(commitmentDomain | numSubCeremonies (uint32) | numSubCeremonies * [numG1Powers (uint32) | numG2Powers (uint32) | powersHash] | numParticipants (uint64) | witnessRoot)
+//
+// where powersHash is the SHA-256 of the compressed G1 and G2 powers of the sub-ceremony, and witnessRoot is the
+// RFC 6962 Merkle tree root of the witness entries of all the participants (see WitnessEntry). This allows
+// proving that a witness entry is in a transcript with a logarithmic Merkle path, see InclusionProof. Integers
+// are big-endian.
+const commitmentDomain = "kzg-ceremony-transcript-commitment-v1"
+
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// WitnessEntry is the data of a participant in the transcript: its id, ECDSA signature and its potPubKey,
+// running product and BLS signature in every sub-ceremony.
+type WitnessEntry struct {
+	ParticipantID   string
+	ECDSASignature  string
+	PotPubKeys      []bls12381.G2Affine
+	RunningProducts []bls12381.G1Affine
+	BLSSignatures   []*bls12381.G1Affine
+}
+
+// WitnessEntry returns the witness entry of the participant at the provided index. The transcript must pass
+// ParameterCheck.
+func (bt *BatchTranscript) WitnessEntry(index int) WitnessEntry {
+	entry := WitnessEntry{
+		ParticipantID:   bt.ParticipantIDs[index],
+		ECDSASignature:  bt.ParticipantECDSASignatures[index],
+		PotPubKeys:      make([]bls12381.G2Affine, len(bt.Transcripts)),
+		RunningProducts: make([]bls12381.G1Affine, len(bt.Transcripts)),
+		BLSSignatures:   make([]*bls12381.G1Affine, len(bt.Transcripts)),
+	}
+	for i, transcript := range bt.Transcripts {
+		entry.PotPubKeys[i] = transcript.Witness.PotPubKeys[index]
+		entry.RunningProducts[i] = transcript.Witness.RunningProducts[index]
+		entry.BLSSignatures[i] = transcript.Witness.BLSSignatures[index]
+	}
+	return entry
+}
+
+// leafHash returns the Merkle leaf hash of the entry:
+//
+//	SHA-256(0x00 | len(participantId) (uint32) | participantId | len(ecdsaSignature) (uint32) | ecdsaSignature | numSubCeremonies * [potPubKey | runningProduct | hasBLSSignature (1 byte) | blsSignature (if any)])
+func (e *WitnessEntry) leafHash() [32]byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	writeString := func(s string) {
+		_ = binary.Write(h, binary.BigEndian, uint32(len(s)))
+		h.Write([]byte(s))
+	}
+	writeString(e.ParticipantID)
+	writeString(e.ECDSASignature)
+	for i := range e.PotPubKeys {
+		potPubKey := e.PotPubKeys[i].Bytes()
+		h.Write(potPubKey[:])
+		runningProduct := e.RunningProducts[i].Bytes()
+		h.Write(runningProduct[:])
+		if e.BLSSignatures[i] == nil {
+			h.Write([]byte{0})
+			continue
+		}
+		h.Write([]byte{1})
+		blsSignature := e.BLSSignatures[i].Bytes()
+		h.Write(blsSignature[:])
+	}
+	var hash [32]byte
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+// SubCeremonyCommitment is the commitment to the powers of a sub-ceremony.
+type SubCeremonyCommitment struct {
+	Parameters contribution.SubCeremonyParameters
+	// PowersHash is the SHA-256 of the compressed G1 and G2 powers.
+	PowersHash [32]byte
+}
+
+// Commitment returns the commitment to the transcript powers and witness, which is also the commitment of the
+// inclusion proofs of the transcript. See InclusionProof.
+func (bt *BatchTranscript) Commitment() ([32]byte, error) {
+	if err := bt.ParameterCheck(bt.Parameters()); err != nil {
+		return [32]byte{}, fmt.Errorf("parameter check: %s", err)
+	}
+	leaves, err := bt.leafHashes()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return commitment(bt.subCeremonyCommitments(), len(leaves), merkleRoot(leaves)), nil
+}
+
+func (bt *BatchTranscript) subCeremonyCommitments() []SubCeremonyCommitment {
+	commitments := make([]SubCeremonyCommitment, len(bt.Transcripts))
+	for i, transcript := range bt.Transcripts {
+		h := sha256.New()
+		for j := range transcript.PowersOfTau.G1Affines {
+			p := transcript.PowersOfTau.G1Affines[j].Bytes()
+			h.Write(p[:])
+		}
+		for j := range transcript.PowersOfTau.G2Affines {
+			p := transcript.PowersOfTau.G2Affines[j].Bytes()
+			h.Write(p[:])
+		}
+		commitments[i].Parameters = contribution.SubCeremonyParameters{NumG1Powers: transcript.NumG1Powers, NumG2Powers: transcript.NumG2Powers}
+		copy(commitments[i].PowersHash[:], h.Sum(nil))
+	}
+	return commitments
+}
+
+func (bt *BatchTranscript) leafHashes() ([][32]byte, error) {
+	if len(bt.ParticipantIDs) == 0 {
+		return nil, fmt.Errorf("the transcript has no participants")
+	}
+	leaves := make([][32]byte, len(bt.ParticipantIDs))
+	err := parallelDecode(len(leaves), func(j int) error {
+		entry := bt.WitnessEntry(j)
+		leaves[j] = entry.leafHash()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("hashing witness entries: %s", err)
+	}
+	return leaves, nil
+}
+
+func commitment(subCeremonies []SubCeremonyCommitment, numParticipants int, witnessRoot [32]byte) [32]byte {
+	h := sha256.New()
+	h.Write([]byte(commitmentDomain))
+	_ = binary.Write(h, binary.BigEndian, uint32(len(subCeremonies)))
+	for _, sc := range subCeremonies {
+		_ = binary.Write(h, binary.BigEndian, uint32(sc.Parameters.NumG1Powers))
+		_ = binary.Write(h, binary.BigEndian, uint32(sc.Parameters.NumG2Powers))
+		h.Write(sc.PowersHash[:])
+	}
+	_ = binary.Write(h, binary.BigEndian, uint64(numParticipants))
+	h.Write(witnessRoot[:])
+	var hash [32]byte
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+func merkleNode(left, right [32]byte) [32]byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left[:])
+	h.Write(right[:])
+	var hash [32]byte
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+// merkleSplit returns the largest power of two smaller than n, which must be greater than one.
+func merkleSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// merkleRoot returns the RFC 6962 Merkle tree root of the (non-empty) leaf hashes.
+func merkleRoot(leaves [][32]byte) [32]byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := merkleSplit(len(leaves))
+	return merkleNode(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// merklePath returns the RFC 6962 audit path of the m-th leaf, from the leaf to the root.
+func merklePath(m int, leaves [][32]byte) [][32]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := merkleSplit(len(leaves))
+	if m < k {
+		return append(merklePath(m, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+	return append(merklePath(m-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+// merklePathRoot returns the root of a tree of size leaves whose m-th leaf is leaf, given its audit path, as
+// described in RFC 9162 section 2.1.3.2. It returns false if the path doesn't have the expected length.
+func merklePathRoot(m int, size int, leaf [32]byte, path [][32]byte) ([32]byte, bool) {
+	if m < 0 || m >= size {
+		return [32]byte{}, false
+	}
+	fn, sn := m, size-1
+	root := leaf
+	for _, p := range path {
+		if sn == 0 {
+			return [32]byte{}, false
+		}
+		if fn&1 == 1 || fn == sn {
+			root = merkleNode(p, root)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			root = merkleNode(root, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return root, sn == 0
+}
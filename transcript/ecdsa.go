@@ -0,0 +1,88 @@
+package transcript
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jsign/go-kzg-ceremony-client/eip712"
+	"golang.org/x/sync/errgroup"
+)
+
+// ECDSASignatureResult is the result of verifying the EIP-712 ECDSA signature of a participant.
+type ECDSASignatureResult struct {
+	ParticipantID string
+	Status        SignatureStatus
+	// Signer is the recovered address of the signature, if any.
+	Signer common.Address
+}
+
+// VerifyECDSASignatures checks the EIP-712 ECDSA signatures of participants. For each signature, the typed data
+// is rebuilt from the participant potPubKeys and the recovered signer must match the address in the `eth|0x...`
+// participant id. Non-Ethereum participants are expected to not have a signature.
+func (bt *BatchTranscript) VerifyECDSASignatures() ([]ECDSASignatureResult, error) {
+	if len(bt.ParticipantECDSASignatures) != len(bt.ParticipantIDs) {
+		return nil, fmt.Errorf("there're %d ecdsa signatures but %d participant ids", len(bt.ParticipantECDSASignatures), len(bt.ParticipantIDs))
+	}
+	for i, transcript := range bt.Transcripts {
+		if len(transcript.Witness.PotPubKeys) != len(bt.ParticipantIDs) {
+			return nil, fmt.Errorf("%d-th transcript has %d potPubKeys but there're %d participant ids", i, len(transcript.Witness.PotPubKeys), len(bt.ParticipantIDs))
+		}
+	}
+
+	results := make([]ECDSASignatureResult, len(bt.ParticipantIDs))
+	var g errgroup.Group
+	g.SetLimit(runtime.NumCPU())
+	for j := range bt.ParticipantIDs {
+		j := j
+		g.Go(func() error {
+			results[j] = ECDSASignatureResult{ParticipantID: bt.ParticipantIDs[j]}
+
+			signature, err := eip712.DecodeSignature(bt.ParticipantECDSASignatures[j])
+			if err != nil {
+				results[j].Status = SignatureInvalid
+				return nil
+			}
+			if signature == nil {
+				results[j].Status = SignatureAbsent
+				return nil
+			}
+
+			address, ok := eip712.AddressFromIdentity(bt.ParticipantIDs[j])
+			if !ok {
+				results[j].Status = SignatureInvalid
+				return nil
+			}
+			signer, err := eip712.RecoverAddress(signature, bt.PotPubKeys(j))
+			if err != nil {
+				results[j].Status = SignatureInvalid
+				return nil
+			}
+			results[j].Signer = signer
+			results[j].Status = SignatureInvalid
+			if signer == address {
+				results[j].Status = SignatureValid
+			}
+
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("verifying ecdsa signatures: %s", err)
+	}
+
+	return results, nil
+}
+
+// PotPubKeys returns the potPubKeys of the participant at the provided index for every sub-ceremony.
+func (bt *BatchTranscript) PotPubKeys(participantIdx int) []eip712.PotPubKey {
+	potPubKeys := make([]eip712.PotPubKey, len(bt.Transcripts))
+	for i, transcript := range bt.Transcripts {
+		potPubKeys[i] = eip712.PotPubKey{
+			NumG1Powers: transcript.NumG1Powers,
+			NumG2Powers: transcript.NumG2Powers,
+			PotPubKey:   transcript.Witness.PotPubKeys[participantIdx],
+		}
+	}
+	return potPubKeys
+}
@@ -0,0 +1,92 @@
+package transcript
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+)
+
+// ErrNotIncluded is returned when a contribution can't be found in the transcript.
+var ErrNotIncluded = errors.New("contribution not included")
+
+// Inclusion describes where a contribution is included in the transcript.
+type Inclusion struct {
+	// Index is the index of the contribution in the participant ids and the witness of every sub-ceremony.
+	Index         int
+	ParticipantID string
+	// PreviousRunningProducts and RunningProducts are the running products before and after the contribution in
+	// each sub-ceremony, which are the witness running products at Index-1 and Index.
+	PreviousRunningProducts []bls12381.G1Affine
+	RunningProducts         []bls12381.G1Affine
+}
+
+// CheckInclusion checks that a contribution is included in the transcript. The contribution potPubKeys must be
+// in the witness of every sub-ceremony at the same index, and the participant id at that index must match
+// participantID (case-insensitive), unless it's empty. For each sub-ceremony, the running product at that index
+// must be the contribution tau first power, and it must be the update of the previous running product with the
+// contribution secret: e(RP[j-1], PK[j]) == e(RP[j], g2).
+// If the potPubKeys aren't found, the returned error wraps ErrNotIncluded.
+func (bt *BatchTranscript) CheckInclusion(bc *contribution.BatchContribution, participantID string) (*Inclusion, error) {
+	if len(bc.Contributions) != len(bt.Transcripts) {
+		return nil, fmt.Errorf("the contribution has %d sub-ceremonies but the transcript has %d", len(bc.Contributions), len(bt.Transcripts))
+	}
+	if len(bt.Transcripts) == 0 {
+		return nil, fmt.Errorf("the transcript has no sub-ceremonies")
+	}
+	for i, transcript := range bt.Transcripts {
+		if len(transcript.Witness.PotPubKeys) != len(bt.ParticipantIDs) || len(transcript.Witness.RunningProducts) != len(bt.ParticipantIDs) {
+			return nil, fmt.Errorf("%d-th transcript has %d potPubKeys and %d running products but there're %d participant ids", i, len(transcript.Witness.PotPubKeys), len(transcript.Witness.RunningProducts), len(bt.ParticipantIDs))
+		}
+	}
+
+	// The index 0 is the initial state, so it can't be a contribution.
+	index := -1
+	for j := 1; j < len(bt.ParticipantIDs); j++ {
+		if bt.Transcripts[0].Witness.PotPubKeys[j].Equal(&bc.Contributions[0].PotPubKey) {
+			index = j
+			break
+		}
+	}
+	if index == -1 {
+		return nil, fmt.Errorf("%w: the potPubKey of the 0-th contribution isn't in the transcript", ErrNotIncluded)
+	}
+	for i := range bt.Transcripts {
+		if !bt.Transcripts[i].Witness.PotPubKeys[index].Equal(&bc.Contributions[i].PotPubKey) {
+			return nil, fmt.Errorf("%w: the potPubKey of the %d-th contribution isn't at index %d as the 0-th one", ErrNotIncluded, i, index)
+		}
+	}
+	if participantID != "" && !strings.EqualFold(bt.ParticipantIDs[index], participantID) {
+		return nil, fmt.Errorf("the contribution at index %d belongs to %s instead of %s", index, bt.ParticipantIDs[index], participantID)
+	}
+
+	inclusion := &Inclusion{
+		Index:                   index,
+		ParticipantID:           bt.ParticipantIDs[index],
+		PreviousRunningProducts: make([]bls12381.G1Affine, len(bt.Transcripts)),
+		RunningProducts:         make([]bls12381.G1Affine, len(bt.Transcripts)),
+	}
+	for i := range bt.Transcripts {
+		witness := &bt.Transcripts[i].Witness
+		inclusion.PreviousRunningProducts[i] = witness.RunningProducts[index-1]
+		inclusion.RunningProducts[i] = witness.RunningProducts[index]
+
+		if len(bc.Contributions[i].PowersOfTau.G1Affines) < 2 {
+			return nil, fmt.Errorf("the %d-th contribution has less than two G1 powers", i)
+		}
+		if !witness.RunningProducts[index].Equal(&bc.Contributions[i].PowersOfTau.G1Affines[1]) {
+			return nil, fmt.Errorf("the running product of the %d-th transcript at index %d isn't the contribution tau first power", i, index)
+		}
+		ok, err := checkTauUpdate(&witness.RunningProducts[index-1], &witness.PotPubKeys[index], &witness.RunningProducts[index])
+		if err != nil {
+			return nil, fmt.Errorf("checking tau update of %d-th transcript: %s", i, err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("the running product of the %d-th transcript at index %d isn't an update with the contribution secret", i, index)
+		}
+	}
+
+	return inclusion, nil
+}
@@ -2,155 +2,205 @@ package transcript
 
 import (
 	"bytes"
-	_ "embed"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-
-	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
-	"github.com/jsign/go-kzg-ceremony-client/contribution"
-	"golang.org/x/sync/errgroup"
+	"sort"
 )
 
 type powersOfTauJSON struct {
 	G1Powers []string `json:"G1Powers"`
 	G2Powers []string `json:"G2Powers"`
+
+	unknownFields map[string]json.RawMessage
 }
 
 type transcriptJSON struct {
-	NumG1Powers int `json:"numG1Powers"`
-	NumG2Powers int `json:"numG2Powers"`
-	PowersOfTau powersOfTauJSON
-	Witness     witnessJSON
+	NumG1Powers int             `json:"numG1Powers"`
+	NumG2Powers int             `json:"numG2Powers"`
+	PowersOfTau powersOfTauJSON `json:"powersOfTau"`
+	Witness     witnessJSON     `json:"witness"`
+
+	unknownFields map[string]json.RawMessage
 }
 
 type witnessJSON struct {
 	RunningProducts []string `json:"runningProducts"`
-	PotPubKeys      []string `json:"potPubKeys"`
+	PotPubKeys      []string `json:"potPubkeys"`
 	BLSSignatures   []string `json:"blsSignatures"`
+
+	unknownFields map[string]json.RawMessage
 }
 
 type batchTranscriptJSON struct {
 	Transcripts                []transcriptJSON `json:"transcripts"`
 	ParticipantIDs             []string         `json:"participantIds"`
 	ParticipantECDSASignatures []string         `json:"participantEcdsaSignatures"`
+
+	unknownFields map[string]json.RawMessage
 }
 
-func Decode(reader io.Reader) (*BatchTranscript, error) {
-	var trJSON batchTranscriptJSON
-	if err := json.NewDecoder(reader).Decode(&trJSON); err != nil {
-		return nil, fmt.Errorf("unmarshaling contribution content: %s", err)
-	}
+func (p powersOfTauJSON) MarshalJSON() ([]byte, error) {
+	type known powersOfTauJSON
+	return encodeObject(known(p), p.unknownFields)
+}
+
+func (t transcriptJSON) MarshalJSON() ([]byte, error) {
+	type known transcriptJSON
+	return encodeObject(known(t), t.unknownFields)
+}
+
+func (w witnessJSON) MarshalJSON() ([]byte, error) {
+	type known witnessJSON
+	return encodeObject(known(w), w.unknownFields)
+}
+
+func (bt batchTranscriptJSON) MarshalJSON() ([]byte, error) {
+	type known batchTranscriptJSON
+	return encodeObject(known(bt), bt.unknownFields)
+}
 
-	bc, err := trJSON.decode()
+// encodeObject encodes the known fields of an object followed by the unknown fields, sorted by name.
+func encodeObject(known interface{}, unknownFields map[string]json.RawMessage) ([]byte, error) {
+	knownJSON, err := marshal(known, false)
 	if err != nil {
-		return nil, fmt.Errorf("subgroup checks: %s", err)
+		return nil, err
+	}
+	if len(unknownFields) == 0 {
+		return knownJSON, nil
+	}
+
+	names := make([]string, 0, len(unknownFields))
+	for name := range unknownFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bytes.NewBuffer(knownJSON[:len(knownJSON)-1])
+	for i, name := range names {
+		if i > 0 || len(knownJSON) > 2 {
+			buf.WriteByte(',')
+		}
+		nameJSON, err := marshal(name, false)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(nameJSON)
+		buf.WriteByte(':')
+		buf.Write(unknownFields[name])
 	}
+	buf.WriteByte('}')
 
-	return bc, nil
+	return buf.Bytes(), nil
 }
 
-func (ts *batchTranscriptJSON) decode() (*BatchTranscript, error) {
-	ret := BatchTranscript{
-		Transcripts:                make([]Transcript, len(ts.Transcripts)),
-		ParticipantIDs:             ts.ParticipantIDs,
-		ParticipantECDSASignatures: ts.ParticipantECDSASignatures,
+// Encode writes the transcript in the same JSON format as the sequencer. Empty BLS signatures are encoded as
+// empty strings, and fields that weren't known when the transcript was decoded are kept.
+func Encode(w io.Writer, bt *BatchTranscript, pretty bool) error {
+	trJSON := encode(bt)
+
+	trJSONBytes, err := marshal(trJSON, pretty)
+	if err != nil {
+		return fmt.Errorf("marshaling transcript: %s", err)
+	}
+	if _, err := w.Write(trJSONBytes); err != nil {
+		return fmt.Errorf("writing transcript: %s", err)
 	}
 
-	var group errgroup.Group
-	for i, transcript := range ts.Transcripts {
-		i, transcriptJSON := i, transcript
-		group.Go(func() error {
-			ret.Transcripts[i] = Transcript{
-				NumG1Powers: transcriptJSON.NumG1Powers,
-				NumG2Powers: transcriptJSON.NumG2Powers,
-				PowersOfTau: contribution.PowersOfTau{
-					G1Affines: make([]bls12381.G1Affine, len(transcriptJSON.PowersOfTau.G1Powers)),
-					G2Affines: make([]bls12381.G2Affine, len(transcriptJSON.PowersOfTau.G2Powers)),
-				},
-				Witness: Witness{
-					RunningProducts: make([]bls12381.G1Affine, len(transcriptJSON.Witness.RunningProducts)),
-					PotPubKeys:      make([]bls12381.G2Affine, len(transcriptJSON.Witness.PotPubKeys)),
-					BLSSignatures:   make([]*bls12381.G1Affine, len(transcriptJSON.Witness.BLSSignatures)),
-				},
-			}
+	return nil
+}
 
-			for j, g1Power := range transcriptJSON.PowersOfTau.G1Powers {
-				g1PowerBytes, err := hex.DecodeString(g1Power[2:])
-				if err != nil {
-					return fmt.Errorf("hex decoding %d-th g1 power in %d contribution: %s", j, i, err)
-				}
-
-				// By default the Decoder *will do* subgroup checking.
-				decoder := bls12381.NewDecoder(bytes.NewReader(g1PowerBytes))
-				var g1Point bls12381.G1Affine
-				if err := decoder.Decode(&g1Point); err != nil {
-					return fmt.Errorf("decoding g1 point: %s", err)
-				}
-				ret.Transcripts[i].PowersOfTau.G1Affines[j] = g1Point
-			}
-			for j, g2Power := range transcriptJSON.PowersOfTau.G2Powers {
-				g2PowerBytes, err := hex.DecodeString(g2Power[2:])
-				if err != nil {
-					return fmt.Errorf("hex decoding %d-th g2 power in %d contribution: %s", j, i, err)
-				}
-
-				// By default the Decoder *will do* subgroup checking.
-				decoder := bls12381.NewDecoder(bytes.NewReader(g2PowerBytes))
-				var g2Point bls12381.G2Affine
-				if err := decoder.Decode(&g2Point); err != nil {
-					return fmt.Errorf("decoding g2 point: %s", err)
-				}
-				ret.Transcripts[i].PowersOfTau.G2Affines[j] = g2Point
-			}
+// marshal is like json.Marshal (or json.MarshalIndent if pretty is true) but without escaping HTML characters,
+// so unknown fields are kept as they were decoded.
+func marshal(v interface{}, pretty bool) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if pretty {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
 
-			for j, runningProduct := range transcriptJSON.Witness.RunningProducts {
-				runningProductBytes, err := hex.DecodeString(runningProduct[2:])
-				if err != nil {
-					return fmt.Errorf("hex decoding %d-th running product in %d transcript: %s", j, i, err)
-				}
-				decoder := bls12381.NewDecoder(bytes.NewReader(runningProductBytes))
-				var g1Point bls12381.G1Affine
-				if err := decoder.Decode(&g1Point); err != nil {
-					return fmt.Errorf("decoding running product: %s", err)
-				}
-				ret.Transcripts[i].Witness.RunningProducts[j] = g1Point
-			}
-			for j, potPubKey := range transcriptJSON.Witness.PotPubKeys {
-				potPubKeyBytes, err := hex.DecodeString(potPubKey[2:])
-				if err != nil {
-					return fmt.Errorf("hex decoding %d-th potpubkey in %d transcript: %s", j, i, err)
-				}
-				decoder := bls12381.NewDecoder(bytes.NewReader(potPubKeyBytes))
-				var g2Point bls12381.G2Affine
-				if err := decoder.Decode(&g2Point); err != nil {
-					return fmt.Errorf("decoding potpubkey product: %s", err)
-				}
-				ret.Transcripts[i].Witness.PotPubKeys[j] = g2Point
-			}
-			for j, blsSignature := range transcriptJSON.Witness.BLSSignatures {
-				if blsSignature == "" {
-					continue
-				}
-				blsSignatureBytes, err := hex.DecodeString(blsSignature[2:])
-				if err != nil {
-					return fmt.Errorf("hex decoding %d-th bls signature in %d transcript: %s", j, i, err)
-				}
-				decoder := bls12381.NewDecoder(bytes.NewReader(blsSignatureBytes))
-				var g1Point bls12381.G1Affine
-				if err := decoder.Decode(&g1Point); err != nil {
-					return fmt.Errorf("decoding bls signature product: %s", err)
-				}
-				ret.Transcripts[i].Witness.BLSSignatures[j] = &g1Point
+func encode(bt *BatchTranscript) batchTranscriptJSON {
+	ret := batchTranscriptJSON{
+		Transcripts:                make([]transcriptJSON, len(bt.Transcripts)),
+		ParticipantIDs:             bt.ParticipantIDs,
+		ParticipantECDSASignatures: bt.ParticipantECDSASignatures,
+		unknownFields:              bt.unknownFields,
+	}
+	for i, transcript := range bt.Transcripts {
+		trJSON := transcriptJSON{
+			NumG1Powers: transcript.NumG1Powers,
+			NumG2Powers: transcript.NumG2Powers,
+			PowersOfTau: powersOfTauJSON{
+				G1Powers:      make([]string, len(transcript.PowersOfTau.G1Affines)),
+				G2Powers:      make([]string, len(transcript.PowersOfTau.G2Affines)),
+				unknownFields: transcript.unknownPowersOfTauFields,
+			},
+			Witness: witnessJSON{
+				RunningProducts: make([]string, len(transcript.Witness.RunningProducts)),
+				PotPubKeys:      make([]string, len(transcript.Witness.PotPubKeys)),
+				BLSSignatures:   make([]string, len(transcript.Witness.BLSSignatures)),
+				unknownFields:   transcript.Witness.unknownFields,
+			},
+			unknownFields: transcript.unknownFields,
+		}
+		for j := range transcript.PowersOfTau.G1Affines {
+			gBytes := transcript.PowersOfTau.G1Affines[j].Bytes()
+			trJSON.PowersOfTau.G1Powers[j] = "0x" + hex.EncodeToString(gBytes[:])
+		}
+		for j := range transcript.PowersOfTau.G2Affines {
+			gBytes := transcript.PowersOfTau.G2Affines[j].Bytes()
+			trJSON.PowersOfTau.G2Powers[j] = "0x" + hex.EncodeToString(gBytes[:])
+		}
+		for j := range transcript.Witness.RunningProducts {
+			gBytes := transcript.Witness.RunningProducts[j].Bytes()
+			trJSON.Witness.RunningProducts[j] = "0x" + hex.EncodeToString(gBytes[:])
+		}
+		for j := range transcript.Witness.PotPubKeys {
+			gBytes := transcript.Witness.PotPubKeys[j].Bytes()
+			trJSON.Witness.PotPubKeys[j] = "0x" + hex.EncodeToString(gBytes[:])
+		}
+		for j, blsSignature := range transcript.Witness.BLSSignatures {
+			// Participants that didn't BLS sign have an empty signature.
+			if blsSignature == nil {
+				continue
 			}
+			gBytes := blsSignature.Bytes()
+			trJSON.Witness.BLSSignatures[j] = "0x" + hex.EncodeToString(gBytes[:])
+		}
+		ret.Transcripts[i] = trJSON
+	}
+	return ret
+}
 
-			return nil
-		})
+// Decode decodes a transcript in the sequencer JSON format. Points are decompressed and subgroup checked while
+// the JSON is streamed, so hex strings are never fully loaded in memory. Invalid values are reported as
+// *contribution.ValidationError with their JSON path.
+func Decode(reader io.Reader) (*BatchTranscript, error) {
+	decoder := NewStreamDecoder(reader)
+	var transcripts []Transcript
+	for {
+		transcript, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		transcripts = append(transcripts, *transcript)
 	}
-	if err := group.Wait(); err != nil {
-		return nil, fmt.Errorf("decoding batch transcript :%s", err)
+	bt, err := decoder.Finish()
+	if err != nil {
+		return nil, err
 	}
+	bt.Transcripts = transcripts
 
-	return &ret, nil
+	return bt, nil
 }
@@ -0,0 +1,294 @@
+package transcript
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+)
+
+// ErrInvalidInclusionProof is returned when an inclusion proof doesn't prove the participant contribution
+// against its transcript commitment.
+var ErrInvalidInclusionProof = errors.New("invalid inclusion proof")
+
+const inclusionProofVersion = 1
+
+// InclusionProof proves that a participant contributed to a transcript with the provided commitment, without
+// needing the transcript. It contains the witness entries of the participant and the previous one with their
+// Merkle paths to the witness root, so the `tau_update_check` of the participant contribution can be checked
+// against the commitment.
+type InclusionProof struct {
+	// Commitment is the commitment of the transcript, see BatchTranscript.Commitment.
+	Commitment    [32]byte
+	SubCeremonies []SubCeremonyCommitment
+	// NumParticipants is the number of witness entries of the transcript, including the initial one.
+	NumParticipants int
+	// Index is the index of the participant witness entry, which is greater than zero.
+	Index        int
+	Participant  WitnessEntry
+	Previous     WitnessEntry
+	Path         [][32]byte
+	PreviousPath [][32]byte
+}
+
+// InclusionProof returns the inclusion proof of the participant at the provided index.
+func (bt *BatchTranscript) InclusionProof(index int) (*InclusionProof, error) {
+	if err := bt.ParameterCheck(bt.Parameters()); err != nil {
+		return nil, fmt.Errorf("parameter check: %s", err)
+	}
+	if index < 1 || index >= len(bt.ParticipantIDs) {
+		return nil, fmt.Errorf("the index %d isn't a contribution, there're %d contributions", index, len(bt.ParticipantIDs)-1)
+	}
+	leaves, err := bt.leafHashes()
+	if err != nil {
+		return nil, err
+	}
+	subCeremonies := bt.subCeremonyCommitments()
+
+	return &InclusionProof{
+		Commitment:      commitment(subCeremonies, len(leaves), merkleRoot(leaves)),
+		SubCeremonies:   subCeremonies,
+		NumParticipants: len(leaves),
+		Index:           index,
+		Participant:     bt.WitnessEntry(index),
+		Previous:        bt.WitnessEntry(index - 1),
+		Path:            merklePath(index, leaves),
+		PreviousPath:    merklePath(index-1, leaves),
+	}, nil
+}
+
+// Verify checks that the witness entries of the participant and the previous one are in the transcript with
+// the proof commitment, and that for each sub-ceremony the participant running product is the update of the
+// previous one with the participant secret: e(RP[j-1], PK[j]) == e(RP[j], g2).
+// Verify doesn't check that the commitment is of a valid transcript, so it must be compared with the commitment
+// of a trusted (i.e: verified) transcript.
+func (p *InclusionProof) Verify() error {
+	if p.Index < 1 || p.Index >= p.NumParticipants {
+		return fmt.Errorf("%w: the index %d isn't a contribution of %d participants", ErrInvalidInclusionProof, p.Index, p.NumParticipants)
+	}
+	entries := []struct {
+		name  string
+		entry *WitnessEntry
+	}{{"participant", &p.Participant}, {"previous", &p.Previous}}
+	for _, e := range entries {
+		if len(e.entry.PotPubKeys) != len(p.SubCeremonies) || len(e.entry.RunningProducts) != len(p.SubCeremonies) || len(e.entry.BLSSignatures) != len(p.SubCeremonies) {
+			return fmt.Errorf("%w: the %s witness entry doesn't have one element per sub-ceremony", ErrInvalidInclusionProof, e.name)
+		}
+	}
+
+	// Both entries must be in the committed witness.
+	root, ok := merklePathRoot(p.Index, p.NumParticipants, p.Participant.leafHash(), p.Path)
+	if !ok {
+		return fmt.Errorf("%w: the participant Merkle path has an invalid length", ErrInvalidInclusionProof)
+	}
+	previousRoot, ok := merklePathRoot(p.Index-1, p.NumParticipants, p.Previous.leafHash(), p.PreviousPath)
+	if !ok {
+		return fmt.Errorf("%w: the previous Merkle path has an invalid length", ErrInvalidInclusionProof)
+	}
+	if root != previousRoot {
+		return fmt.Errorf("%w: the participant and previous witness entries aren't in the same witness", ErrInvalidInclusionProof)
+	}
+	if commitment(p.SubCeremonies, p.NumParticipants, root) != p.Commitment {
+		return fmt.Errorf("%w: the witness entries don't match the commitment", ErrInvalidInclusionProof)
+	}
+
+	for i := range p.SubCeremonies {
+		ok, err := checkTauUpdate(&p.Previous.RunningProducts[i], &p.Participant.PotPubKeys[i], &p.Participant.RunningProducts[i])
+		if err != nil {
+			return fmt.Errorf("checking tau update of %d-th sub-ceremony: %s", i, err)
+		}
+		if !ok {
+			return fmt.Errorf("%w: the running product of the %d-th sub-ceremony isn't an update with the participant potPubKey", ErrInvalidInclusionProof, i)
+		}
+	}
+
+	return nil
+}
+
+type inclusionProofJSON struct {
+	Version         int                         `json:"version"`
+	Commitment      string                      `json:"transcriptCommitment"`
+	SubCeremonies   []subCeremonyCommitmentJSON `json:"subCeremonies"`
+	NumParticipants int                         `json:"numParticipants"`
+	Index           int                         `json:"index"`
+	Participant     witnessEntryJSON            `json:"participant"`
+	Previous        witnessEntryJSON            `json:"previous"`
+	Path            []string                    `json:"merklePath"`
+	PreviousPath    []string                    `json:"previousMerklePath"`
+}
+
+type subCeremonyCommitmentJSON struct {
+	NumG1Powers int    `json:"numG1Powers"`
+	NumG2Powers int    `json:"numG2Powers"`
+	PowersHash  string `json:"powersHash"`
+}
+
+type witnessEntryJSON struct {
+	ParticipantID   string   `json:"participantId"`
+	ECDSASignature  string   `json:"ecdsaSignature"`
+	PotPubKeys      []string `json:"potPubkeys"`
+	RunningProducts []string `json:"runningProducts"`
+	BLSSignatures   []string `json:"blsSignatures"`
+}
+
+// EncodeInclusionProof encodes the inclusion proof in JSON, with hex encoded hashes and compressed points as in
+// the transcript format.
+func EncodeInclusionProof(p *InclusionProof, pretty bool) ([]byte, error) {
+	proofJSON := inclusionProofJSON{
+		Version:         inclusionProofVersion,
+		Commitment:      hashHex(p.Commitment),
+		SubCeremonies:   make([]subCeremonyCommitmentJSON, len(p.SubCeremonies)),
+		NumParticipants: p.NumParticipants,
+		Index:           p.Index,
+		Participant:     encodeWitnessEntry(&p.Participant),
+		Previous:        encodeWitnessEntry(&p.Previous),
+		Path:            encodeMerklePath(p.Path),
+		PreviousPath:    encodeMerklePath(p.PreviousPath),
+	}
+	for i, sc := range p.SubCeremonies {
+		proofJSON.SubCeremonies[i] = subCeremonyCommitmentJSON{
+			NumG1Powers: sc.Parameters.NumG1Powers,
+			NumG2Powers: sc.Parameters.NumG2Powers,
+			PowersHash:  hashHex(sc.PowersHash),
+		}
+	}
+	return marshal(proofJSON, pretty)
+}
+
+func encodeWitnessEntry(e *WitnessEntry) witnessEntryJSON {
+	entryJSON := witnessEntryJSON{
+		ParticipantID:   e.ParticipantID,
+		ECDSASignature:  e.ECDSASignature,
+		PotPubKeys:      make([]string, len(e.PotPubKeys)),
+		RunningProducts: make([]string, len(e.RunningProducts)),
+		BLSSignatures:   make([]string, len(e.BLSSignatures)),
+	}
+	for i := range e.PotPubKeys {
+		pBytes := e.PotPubKeys[i].Bytes()
+		entryJSON.PotPubKeys[i] = "0x" + hex.EncodeToString(pBytes[:])
+	}
+	for i := range e.RunningProducts {
+		pBytes := e.RunningProducts[i].Bytes()
+		entryJSON.RunningProducts[i] = "0x" + hex.EncodeToString(pBytes[:])
+	}
+	for i, sig := range e.BLSSignatures {
+		if sig != nil {
+			pBytes := sig.Bytes()
+			entryJSON.BLSSignatures[i] = "0x" + hex.EncodeToString(pBytes[:])
+		}
+	}
+	return entryJSON
+}
+
+func encodeMerklePath(path [][32]byte) []string {
+	pathJSON := make([]string, len(path))
+	for i, hash := range path {
+		pathJSON[i] = hashHex(hash)
+	}
+	return pathJSON
+}
+
+func hashHex(hash [32]byte) string {
+	return "0x" + hex.EncodeToString(hash[:])
+}
+
+// DecodeInclusionProof decodes a JSON inclusion proof. All the points are subgroup checked, but the proof isn't
+// verified, see InclusionProof.Verify.
+func DecodeInclusionProof(b []byte) (*InclusionProof, error) {
+	var proofJSON inclusionProofJSON
+	if err := json.Unmarshal(b, &proofJSON); err != nil {
+		return nil, fmt.Errorf("unmarshaling inclusion proof: %s", err)
+	}
+	if proofJSON.Version != inclusionProofVersion {
+		return nil, fmt.Errorf("unsupported inclusion proof version %d", proofJSON.Version)
+	}
+
+	p := &InclusionProof{
+		SubCeremonies:   make([]SubCeremonyCommitment, len(proofJSON.SubCeremonies)),
+		NumParticipants: proofJSON.NumParticipants,
+		Index:           proofJSON.Index,
+	}
+	if err := contribution.DecodeHex("transcriptCommitment", proofJSON.Commitment, p.Commitment[:]); err != nil {
+		return nil, err
+	}
+	for i, sc := range proofJSON.SubCeremonies {
+		p.SubCeremonies[i].Parameters = contribution.SubCeremonyParameters{NumG1Powers: sc.NumG1Powers, NumG2Powers: sc.NumG2Powers}
+		path := contribution.JoinPath(contribution.IndexPath("subCeremonies", i), "powersHash")
+		if err := contribution.DecodeHex(path, sc.PowersHash, p.SubCeremonies[i].PowersHash[:]); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if p.Participant, err = decodeWitnessEntry("participant", &proofJSON.Participant); err != nil {
+		return nil, err
+	}
+	if p.Previous, err = decodeWitnessEntry("previous", &proofJSON.Previous); err != nil {
+		return nil, err
+	}
+	if p.Path, err = decodeMerklePath("merklePath", proofJSON.Path); err != nil {
+		return nil, err
+	}
+	if p.PreviousPath, err = decodeMerklePath("previousMerklePath", proofJSON.PreviousPath); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func decodeWitnessEntry(path string, entryJSON *witnessEntryJSON) (WitnessEntry, error) {
+	entry := WitnessEntry{
+		ParticipantID:   entryJSON.ParticipantID,
+		ECDSASignature:  entryJSON.ECDSASignature,
+		PotPubKeys:      make([]bls12381.G2Affine, len(entryJSON.PotPubKeys)),
+		RunningProducts: make([]bls12381.G1Affine, len(entryJSON.RunningProducts)),
+		BLSSignatures:   make([]*bls12381.G1Affine, len(entryJSON.BLSSignatures)),
+	}
+	for i, potPubKey := range entryJSON.PotPubKeys {
+		pointPath := contribution.IndexPath(contribution.JoinPath(path, "potPubkeys"), i)
+		b, err := contribution.SchemaCheckHex(pointPath, potPubKey, bls12381.SizeOfG2AffineCompressed)
+		if err != nil {
+			return WitnessEntry{}, err
+		}
+		if err := contribution.DecodeG2Point(pointPath, b, &entry.PotPubKeys[i]); err != nil {
+			return WitnessEntry{}, err
+		}
+	}
+	for i, runningProduct := range entryJSON.RunningProducts {
+		pointPath := contribution.IndexPath(contribution.JoinPath(path, "runningProducts"), i)
+		b, err := contribution.SchemaCheckHex(pointPath, runningProduct, bls12381.SizeOfG1AffineCompressed)
+		if err != nil {
+			return WitnessEntry{}, err
+		}
+		if err := contribution.DecodeG1Point(pointPath, b, &entry.RunningProducts[i]); err != nil {
+			return WitnessEntry{}, err
+		}
+	}
+	for i, sig := range entryJSON.BLSSignatures {
+		if sig == "" {
+			continue
+		}
+		pointPath := contribution.IndexPath(contribution.JoinPath(path, "blsSignatures"), i)
+		b, err := contribution.SchemaCheckHex(pointPath, sig, bls12381.SizeOfG1AffineCompressed)
+		if err != nil {
+			return WitnessEntry{}, err
+		}
+		entry.BLSSignatures[i] = &bls12381.G1Affine{}
+		if err := contribution.DecodeG1Point(pointPath, b, entry.BLSSignatures[i]); err != nil {
+			return WitnessEntry{}, err
+		}
+	}
+	return entry, nil
+}
+
+func decodeMerklePath(path string, pathJSON []string) ([][32]byte, error) {
+	hashes := make([][32]byte, len(pathJSON))
+	for i, hash := range pathJSON {
+		if err := contribution.DecodeHex(contribution.IndexPath(path, i), hash, hashes[i][:]); err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
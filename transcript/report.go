@@ -0,0 +1,385 @@
+package transcript
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"golang.org/x/sync/errgroup"
+)
+
+// Check is the name of a verification check of the spec.
+type Check string
+
+const (
+	CheckSchema          Check = "schema"
+	CheckParameters      Check = "parameters"
+	CheckSubgroup        Check = "subgroup"
+	CheckTauUpdate       Check = "tau_update"
+	CheckG1Powers        Check = "g1_powers"
+	CheckG2Powers        Check = "g2_powers"
+	CheckBLSSignatures   Check = "bls_signatures"
+	CheckECDSASignatures Check = "ecdsa_signatures"
+)
+
+// CheckResult is the result of one of the checks of a VerifyReport.
+type CheckResult struct {
+	Check Check `json:"check"`
+	// SubCeremony is the index of the checked sub-ceremony, or -1 if the check isn't about a single sub-ceremony.
+	SubCeremony int  `json:"subCeremony"`
+	Passed      bool `json:"passed"`
+	// Checked is the number of equations or elements that were checked.
+	Checked  int            `json:"checked"`
+	Failures []CheckFailure `json:"failures,omitempty"`
+	// Signatures counts the signatures by status, only in the signature checks.
+	Signatures *SignatureCounts `json:"signatures,omitempty"`
+	// Duration is the time spent in the check, encoded in nanoseconds in JSON.
+	Duration time.Duration `json:"durationNs"`
+}
+
+// SignatureCounts are the number of valid, invalid and absent signatures of a signature check.
+type SignatureCounts struct {
+	Valid   int `json:"valid"`
+	Invalid int `json:"invalid"`
+	Absent  int `json:"absent"`
+}
+
+// count adds a signature with the status.
+func (sc *SignatureCounts) count(status SignatureStatus) {
+	switch status {
+	case SignatureValid:
+		sc.Valid++
+	case SignatureInvalid:
+		sc.Invalid++
+	default:
+		sc.Absent++
+	}
+}
+
+// CheckFailure describes a failing element of a check.
+type CheckFailure struct {
+	// Index is the index of the failing power, witness element or participant, or -1 if the failure isn't about
+	// a single element.
+	Index int `json:"index"`
+	// Participant is the id of the participant involved in the failure, if any.
+	Participant string `json:"participant,omitempty"`
+	// Path is the JSON path of the failing value, if any.
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// VerifyReport lists the results of every check that ran to verify a transcript.
+type VerifyReport struct {
+	Checks []CheckResult `json:"checks"`
+	// Duration is the total time spent in the checks, encoded in nanoseconds in JSON.
+	Duration time.Duration `json:"durationNs"`
+}
+
+// VerifyOptions configures the checks of BatchTranscript.VerifyReport.
+type VerifyOptions struct {
+	// Parameters are the expected ceremony parameters. If empty, the declared ones in the transcript are used.
+	Parameters []contribution.SubCeremonyParameters
+	// Exhaustive checks each pairing equation independently instead of batching them with random linear
+	// combinations. In any case, the failing equations of a failed batched check are located exhaustively.
+	Exhaustive bool
+	// BLSSignatures also checks the optional BLS signatures of participant identities.
+	BLSSignatures bool
+}
+
+// Passed returns true if all the checks passed.
+func (r *VerifyReport) Passed() bool {
+	return r.Err() == nil
+}
+
+// Err returns an error describing the first failed check, or nil if all the checks passed.
+func (r *VerifyReport) Err() error {
+	for _, result := range r.Checks {
+		if !result.Passed {
+			return result.err()
+		}
+	}
+	return nil
+}
+
+func (cr *CheckResult) err() error {
+	where := "transcript"
+	if cr.SubCeremony >= 0 {
+		where = fmt.Sprintf("%d-th transcript", cr.SubCeremony)
+	}
+	if len(cr.Failures) == 0 {
+		return fmt.Errorf("%s check of %s failed", cr.Check, where)
+	}
+	return fmt.Errorf("%s check of %s failed with %d failures, the first one: %s", cr.Check, where, len(cr.Failures), cr.Failures[0].Message)
+}
+
+// DecodeCheckResults returns the results of the `schema_check` and `subgroup_checks`, which run interleaved while
+// decoding a transcript (see Decode), so both share the decoding duration. bt is the decoded transcript and err
+// the decoding error, and only the failing check is returned in that case.
+func DecodeCheckResults(bt *BatchTranscript, err error, duration time.Duration) []CheckResult {
+	if err == nil {
+		var numPoints int
+		for _, t := range bt.Transcripts {
+			numPoints += len(t.PowersOfTau.G1Affines) + len(t.PowersOfTau.G2Affines)
+			numPoints += len(t.Witness.RunningProducts) + len(t.Witness.PotPubKeys)
+			for _, signature := range t.Witness.BLSSignatures {
+				if signature != nil {
+					numPoints++
+				}
+			}
+		}
+		return []CheckResult{
+			{Check: CheckSchema, SubCeremony: -1, Passed: true, Checked: numPoints, Duration: duration},
+			{Check: CheckSubgroup, SubCeremony: -1, Passed: true, Checked: numPoints, Duration: duration},
+		}
+	}
+
+	failure := CheckFailure{Index: -1, Message: err.Error()}
+	var validationErr *contribution.ValidationError
+	if errors.As(err, &validationErr) {
+		failure.Path = validationErr.Path
+	}
+	check := CheckSchema
+	if errors.Is(err, contribution.ErrInvalidPoint) {
+		check = CheckSubgroup
+	}
+	return []CheckResult{{Check: check, SubCeremony: -1, Failures: []CheckFailure{failure}, Duration: duration}}
+}
+
+// VerifyReport runs all the checks of the transcript and reports the result of each of them. Contrary to Verify,
+// it doesn't stop at the first failure, except if the parameters check fails since the rest of the checks
+// can't run.
+func (bt *BatchTranscript) VerifyReport(opts VerifyOptions) *VerifyReport {
+	start := time.Now()
+	report := &VerifyReport{}
+
+	// 1. `schema_check` and 3. `subgroup_checks` were done when decoding, see DecodeCheckResults.
+
+	// 2. `parameter_check`.
+	params := opts.Parameters
+	if len(params) == 0 {
+		params = bt.Parameters()
+	}
+	report.Checks = append(report.Checks, timeCheck(CheckResult{Check: CheckParameters, SubCeremony: -1, Checked: len(bt.Transcripts)}, func(result *CheckResult) {
+		if err := bt.ParameterCheck(params); err != nil {
+			failure := CheckFailure{Index: -1, Message: err.Error()}
+			var validationErr *contribution.ValidationError
+			if errors.As(err, &validationErr) {
+				failure.Path = validationErr.Path
+			}
+			result.Failures = append(result.Failures, failure)
+		}
+	}))
+	if !report.Checks[len(report.Checks)-1].Passed {
+		report.Duration = time.Since(start)
+		return report
+	}
+
+	// 4, 5 and 6. `tau_update_check`, `g1_powers_check` and `g2_powers_check`.
+	for i := range bt.Transcripts {
+		report.Checks = append(report.Checks, bt.powersChecks(i, opts.Exhaustive)...)
+	}
+
+	report.Checks = append(report.Checks, timeCheck(CheckResult{Check: CheckECDSASignatures, SubCeremony: -1}, func(result *CheckResult) {
+		results, err := bt.VerifyECDSASignatures()
+		if err != nil {
+			result.Failures = append(result.Failures, CheckFailure{Index: -1, Message: err.Error()})
+			return
+		}
+		result.Signatures = &SignatureCounts{}
+		for j, r := range results {
+			result.Signatures.count(r.Status)
+			if r.Status == SignatureAbsent {
+				continue
+			}
+			result.Checked++
+			if r.Status == SignatureInvalid {
+				result.Failures = append(result.Failures, CheckFailure{Index: j, Participant: r.ParticipantID, Path: contribution.IndexPath("participantEcdsaSignatures", j), Message: fmt.Sprintf("invalid signature (signer: %s)", r.Signer.Hex())})
+			}
+		}
+	}))
+
+	if opts.BLSSignatures {
+		report.Checks = append(report.Checks, timeCheck(CheckResult{Check: CheckBLSSignatures, SubCeremony: -1}, func(result *CheckResult) {
+			results, err := bt.VerifyBLSSignatures()
+			if err != nil {
+				result.Failures = append(result.Failures, CheckFailure{Index: -1, Message: err.Error()})
+				return
+			}
+			result.Signatures = &SignatureCounts{}
+			for j, r := range results {
+				for _, status := range r.SubCeremonies {
+					result.Signatures.count(status)
+					if status != SignatureAbsent {
+						result.Checked++
+					}
+				}
+				if r.Status == SignatureInvalid {
+					result.Failures = append(result.Failures, CheckFailure{Index: j, Participant: r.ParticipantID, Message: fmt.Sprintf("the sub-ceremonies signatures aren't all valid: %v", r.SubCeremonies)})
+				}
+			}
+		}))
+	}
+
+	report.Duration = time.Since(start)
+	return report
+}
+
+// powersChecks runs the `tau_update_check`, `g1_powers_check` and `g2_powers_check` of the i-th transcript. If
+// exhaustive is false the equations of each check are batched, and only checked one by one if the batched check
+// fails to report the failing ones.
+func (bt *BatchTranscript) powersChecks(i int, exhaustive bool) []CheckResult {
+	t := &bt.Transcripts[i]
+	// The current powers were produced by the last participant.
+	var lastParticipant string
+	if len(bt.ParticipantIDs) > 0 {
+		lastParticipant = bt.ParticipantIDs[len(bt.ParticipantIDs)-1]
+	}
+
+	tauUpdate := timeCheck(CheckResult{Check: CheckTauUpdate, SubCeremony: i, Checked: len(t.Witness.RunningProducts) - 1}, func(result *CheckResult) {
+		path := contribution.JoinPath(contribution.IndexPath("transcripts", i), "witness.runningProducts")
+		if len(t.Witness.RunningProducts) == 0 {
+			result.Checked = 0
+			result.Failures = append(result.Failures, CheckFailure{Index: -1, Path: path, Message: "there're no running products"})
+			return
+		}
+		if !t.Witness.RunningProducts[0].Equal(&t.PowersOfTau.G1Affines[0]) {
+			result.Failures = append(result.Failures, CheckFailure{Index: 0, Participant: bt.ParticipantIDs[0], Path: contribution.IndexPath(path, 0), Message: "the first running product isn't the generator"})
+		}
+		last := len(t.Witness.RunningProducts) - 1
+		if !t.Witness.RunningProducts[last].Equal(&t.PowersOfTau.G1Affines[1]) {
+			result.Failures = append(result.Failures, CheckFailure{Index: last, Participant: bt.ParticipantIDs[last], Path: contribution.IndexPath(path, last), Message: "the last running product doesn't match tau first power"})
+		}
+		if !exhaustive {
+			ok, err := t.batchPairingCheck(nil, nil)
+			if err != nil {
+				result.Failures = append(result.Failures, CheckFailure{Index: -1, Message: fmt.Sprintf("batched pairing check: %s", err)})
+				return
+			}
+			if ok {
+				return
+			}
+		}
+		// e(RP[j], PK[j+1]) == e(RP[j+1], g2) for the update of the (j+1)-th participant.
+		failing, err := failingEquations(last, func(j int) (bool, error) {
+			return checkTauUpdate(&t.Witness.RunningProducts[j], &t.Witness.PotPubKeys[j+1], &t.Witness.RunningProducts[j+1])
+		})
+		if err != nil {
+			result.Failures = append(result.Failures, CheckFailure{Index: -1, Message: err.Error()})
+			return
+		}
+		for _, j := range failing {
+			result.Failures = append(result.Failures, CheckFailure{Index: j + 1, Participant: bt.ParticipantIDs[j+1], Path: contribution.IndexPath(path, j+1), Message: fmt.Sprintf("the running product of the %d-th participant isn't an update with its potPubKey", j+1)})
+		}
+	})
+
+	g1Powers := timeCheck(CheckResult{Check: CheckG1Powers, SubCeremony: i, Checked: len(t.PowersOfTau.G1Affines) - 1}, func(result *CheckResult) {
+		if !exhaustive {
+			g1Pairs, g2Pairs, err := t.PowersOfTau.G1PowersCheckPairs()
+			if err != nil {
+				result.Failures = append(result.Failures, CheckFailure{Index: -1, Message: err.Error()})
+				return
+			}
+			ok, err := bls12381.PairingCheck(g1Pairs, g2Pairs)
+			if err != nil {
+				result.Failures = append(result.Failures, CheckFailure{Index: -1, Message: fmt.Sprintf("batched pairing check: %s", err)})
+				return
+			}
+			if ok {
+				return
+			}
+		}
+		// e(G1[j], G2[1]) == e(G1[j+1], g2)
+		failing, err := failingEquations(len(t.PowersOfTau.G1Affines)-1, func(j int) (bool, error) {
+			var negNextG1 bls12381.G1Affine
+			negNextG1.Neg(&t.PowersOfTau.G1Affines[j+1])
+			return bls12381.PairingCheck(
+				[]bls12381.G1Affine{t.PowersOfTau.G1Affines[j], negNextG1},
+				[]bls12381.G2Affine{t.PowersOfTau.G2Affines[1], g2Generator})
+		})
+		if err != nil {
+			result.Failures = append(result.Failures, CheckFailure{Index: -1, Message: err.Error()})
+			return
+		}
+		path := contribution.JoinPath(contribution.IndexPath("transcripts", i), "powersOfTau.G1Powers")
+		for _, j := range failing {
+			result.Failures = append(result.Failures, CheckFailure{Index: j + 1, Participant: lastParticipant, Path: contribution.IndexPath(path, j+1), Message: fmt.Sprintf("the %d-th G1 power isn't consistent with the previous one", j+1)})
+		}
+	})
+
+	g2Powers := timeCheck(CheckResult{Check: CheckG2Powers, SubCeremony: i, Checked: len(t.PowersOfTau.G2Affines) - 1}, func(result *CheckResult) {
+		if !exhaustive {
+			g1Pairs, g2Pairs, err := t.PowersOfTau.G2PowersCheckPairs()
+			if err != nil {
+				result.Failures = append(result.Failures, CheckFailure{Index: -1, Message: err.Error()})
+				return
+			}
+			ok, err := bls12381.PairingCheck(g1Pairs, g2Pairs)
+			if err != nil {
+				result.Failures = append(result.Failures, CheckFailure{Index: -1, Message: fmt.Sprintf("batched pairing check: %s", err)})
+				return
+			}
+			if ok {
+				return
+			}
+		}
+		// e(G1[1], G2[j]) == e(g1, G2[j+1])
+		var negG1Generator bls12381.G1Affine
+		negG1Generator.Neg(&g1Generator)
+		failing, err := failingEquations(len(t.PowersOfTau.G2Affines)-1, func(j int) (bool, error) {
+			return bls12381.PairingCheck(
+				[]bls12381.G1Affine{t.PowersOfTau.G1Affines[1], negG1Generator},
+				[]bls12381.G2Affine{t.PowersOfTau.G2Affines[j], t.PowersOfTau.G2Affines[j+1]})
+		})
+		if err != nil {
+			result.Failures = append(result.Failures, CheckFailure{Index: -1, Message: err.Error()})
+			return
+		}
+		path := contribution.JoinPath(contribution.IndexPath("transcripts", i), "powersOfTau.G2Powers")
+		for _, j := range failing {
+			result.Failures = append(result.Failures, CheckFailure{Index: j + 1, Participant: lastParticipant, Path: contribution.IndexPath(path, j+1), Message: fmt.Sprintf("the %d-th G2 power isn't consistent with the previous one", j+1)})
+		}
+	})
+
+	return []CheckResult{tauUpdate, g1Powers, g2Powers}
+}
+
+// timeCheck runs the check and sets its duration. The check passes if it doesn't add any failure.
+func timeCheck(result CheckResult, check func(result *CheckResult)) CheckResult {
+	start := time.Now()
+	check(&result)
+	result.Duration = time.Since(start)
+	result.Passed = len(result.Failures) == 0
+	return result
+}
+
+// failingEquations checks n equations using all the cores, and returns the sorted indices of the failing ones.
+func failingEquations(n int, check func(j int) (bool, error)) ([]int, error) {
+	failed := make([]bool, n)
+	var g errgroup.Group
+	g.SetLimit(runtime.NumCPU())
+	for j := 0; j < n; j++ {
+		j := j
+		g.Go(func() error {
+			ok, err := check(j)
+			if err != nil {
+				return fmt.Errorf("checking %d-th equation: %s", j, err)
+			}
+			failed[j] = !ok
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var failing []int
+	for j, f := range failed {
+		if f {
+			failing = append(failing, j)
+		}
+	}
+	return failing, nil
+}
@@ -0,0 +1,378 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"golang.org/x/sync/errgroup"
+)
+
+// pointsChunkSize is the number of hex encoded points buffered before decoding them in parallel.
+const pointsChunkSize = 1024
+
+const (
+	streamStart = iota
+	streamInObject
+	streamInTranscripts
+	streamDone
+)
+
+// StreamDecoder decodes a transcript from a JSON stream without loading it in memory. Points are schema checked,
+// decompressed and subgroup checked as they arrive, and the sub-transcripts are returned one at a time by Next,
+// so the caller can drop each of them after using it. Invalid values are reported as
+// *contribution.ValidationError with their JSON path.
+type StreamDecoder struct {
+	decoder *json.Decoder
+	state   int
+	// bt contains the decoded fields of the batch transcript, except the sub-transcripts.
+	bt             BatchTranscript
+	numTranscripts int
+}
+
+// NewStreamDecoder returns a StreamDecoder that reads the transcript from r.
+func NewStreamDecoder(r io.Reader) *StreamDecoder {
+	return &StreamDecoder{decoder: json.NewDecoder(r)}
+}
+
+// Next decodes the next sub-transcript. It returns io.EOF when there're no more sub-transcripts.
+func (d *StreamDecoder) Next() (*Transcript, error) {
+	for {
+		switch d.state {
+		case streamStart:
+			if err := d.expectDelim("", '{'); err != nil {
+				return nil, err
+			}
+			d.state = streamInObject
+		case streamInTranscripts:
+			if d.decoder.More() {
+				transcript, err := d.decodeTranscript(contribution.IndexPath("transcripts", d.numTranscripts))
+				if err != nil {
+					return nil, err
+				}
+				d.numTranscripts++
+				return transcript, nil
+			}
+			if err := d.expectDelim("transcripts", ']'); err != nil {
+				return nil, err
+			}
+			d.state = streamInObject
+		case streamInObject:
+			if !d.decoder.More() {
+				if err := d.expectDelim("", '}'); err != nil {
+					return nil, err
+				}
+				d.state = streamDone
+				continue
+			}
+			name, err := d.fieldName("")
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case strings.EqualFold(name, "transcripts"):
+				if err := d.expectDelim("transcripts", '['); err != nil {
+					return nil, err
+				}
+				d.state = streamInTranscripts
+			case strings.EqualFold(name, "participantIds"):
+				if err := d.decodeValue("participantIds", &d.bt.ParticipantIDs); err != nil {
+					return nil, err
+				}
+			case strings.EqualFold(name, "participantEcdsaSignatures"):
+				if err := d.decodeValue("participantEcdsaSignatures", &d.bt.ParticipantECDSASignatures); err != nil {
+					return nil, err
+				}
+			default:
+				if err := d.decodeUnknownField("", name, &d.bt.unknownFields); err != nil {
+					return nil, err
+				}
+			}
+		case streamDone:
+			return nil, io.EOF
+		}
+	}
+}
+
+// Finish decodes the rest of the stream, and returns the batch transcript without the sub-transcripts, which
+// are returned by Next. It fails if not all the sub-transcripts were consumed.
+func (d *StreamDecoder) Finish() (*BatchTranscript, error) {
+	_, err := d.Next()
+	if err == nil {
+		return nil, fmt.Errorf("the %d-th transcript wasn't consumed", d.numTranscripts-1)
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	bt := d.bt
+	return &bt, nil
+}
+
+func (d *StreamDecoder) decodeTranscript(path string) (*Transcript, error) {
+	var transcript Transcript
+	if err := d.expectDelim(path, '{'); err != nil {
+		return nil, err
+	}
+	for d.decoder.More() {
+		name, err := d.fieldName(path)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.EqualFold(name, "numG1Powers"):
+			if err := d.decodeValue(contribution.JoinPath(path, "numG1Powers"), &transcript.NumG1Powers); err != nil {
+				return nil, err
+			}
+		case strings.EqualFold(name, "numG2Powers"):
+			if err := d.decodeValue(contribution.JoinPath(path, "numG2Powers"), &transcript.NumG2Powers); err != nil {
+				return nil, err
+			}
+		case strings.EqualFold(name, "powersOfTau"):
+			if err := d.decodePowersOfTau(contribution.JoinPath(path, "powersOfTau"), &transcript); err != nil {
+				return nil, err
+			}
+		case strings.EqualFold(name, "witness"):
+			if err := d.decodeWitness(contribution.JoinPath(path, "witness"), &transcript.Witness); err != nil {
+				return nil, err
+			}
+		default:
+			if err := d.decodeUnknownField(path, name, &transcript.unknownFields); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := d.expectDelim(path, '}'); err != nil {
+		return nil, err
+	}
+	return &transcript, nil
+}
+
+func (d *StreamDecoder) decodePowersOfTau(path string, transcript *Transcript) error {
+	if err := d.expectDelim(path, '{'); err != nil {
+		return err
+	}
+	for d.decoder.More() {
+		name, err := d.fieldName(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.EqualFold(name, "G1Powers"):
+			if transcript.PowersOfTau.G1Affines, err = d.decodeG1Points(contribution.JoinPath(path, "G1Powers")); err != nil {
+				return err
+			}
+		case strings.EqualFold(name, "G2Powers"):
+			if transcript.PowersOfTau.G2Affines, err = d.decodeG2Points(contribution.JoinPath(path, "G2Powers")); err != nil {
+				return err
+			}
+		default:
+			if err := d.decodeUnknownField(path, name, &transcript.unknownPowersOfTauFields); err != nil {
+				return err
+			}
+		}
+	}
+	return d.expectDelim(path, '}')
+}
+
+func (d *StreamDecoder) decodeWitness(path string, witness *Witness) error {
+	if err := d.expectDelim(path, '{'); err != nil {
+		return err
+	}
+	for d.decoder.More() {
+		name, err := d.fieldName(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.EqualFold(name, "runningProducts"):
+			if witness.RunningProducts, err = d.decodeG1Points(contribution.JoinPath(path, "runningProducts")); err != nil {
+				return err
+			}
+		case strings.EqualFold(name, "potPubkeys"):
+			if witness.PotPubKeys, err = d.decodeG2Points(contribution.JoinPath(path, "potPubkeys")); err != nil {
+				return err
+			}
+		case strings.EqualFold(name, "blsSignatures"):
+			if witness.BLSSignatures, err = d.decodeBLSSignatures(contribution.JoinPath(path, "blsSignatures")); err != nil {
+				return err
+			}
+		default:
+			if err := d.decodeUnknownField(path, name, &witness.unknownFields); err != nil {
+				return err
+			}
+		}
+	}
+	return d.expectDelim(path, '}')
+}
+
+func (d *StreamDecoder) decodeG1Points(path string) ([]bls12381.G1Affine, error) {
+	var points []bls12381.G1Affine
+	err := d.decodePoints(path, bls12381.SizeOfG1AffineCompressed, false, func(offset int, chunk [][]byte) error {
+		points = append(points, make([]bls12381.G1Affine, len(chunk))...)
+		return parallelDecode(len(chunk), func(i int) error {
+			return contribution.DecodeG1Point(contribution.IndexPath(path, offset+i), chunk[i], &points[offset+i])
+		})
+	})
+	return points, err
+}
+
+func (d *StreamDecoder) decodeG2Points(path string) ([]bls12381.G2Affine, error) {
+	var points []bls12381.G2Affine
+	err := d.decodePoints(path, bls12381.SizeOfG2AffineCompressed, false, func(offset int, chunk [][]byte) error {
+		points = append(points, make([]bls12381.G2Affine, len(chunk))...)
+		return parallelDecode(len(chunk), func(i int) error {
+			return contribution.DecodeG2Point(contribution.IndexPath(path, offset+i), chunk[i], &points[offset+i])
+		})
+	})
+	return points, err
+}
+
+// decodeBLSSignatures decodes the BLS signatures of a witness, where participants that didn't sign have an
+// empty string signature that is decoded as nil.
+func (d *StreamDecoder) decodeBLSSignatures(path string) ([]*bls12381.G1Affine, error) {
+	var signatures []*bls12381.G1Affine
+	err := d.decodePoints(path, bls12381.SizeOfG1AffineCompressed, true, func(offset int, chunk [][]byte) error {
+		signatures = append(signatures, make([]*bls12381.G1Affine, len(chunk))...)
+		return parallelDecode(len(chunk), func(i int) error {
+			if chunk[i] == nil {
+				return nil
+			}
+			var signature bls12381.G1Affine
+			if err := contribution.DecodeG1Point(contribution.IndexPath(path, offset+i), chunk[i], &signature); err != nil {
+				return err
+			}
+			signatures[offset+i] = &signature
+			return nil
+		})
+	})
+	return signatures, err
+}
+
+// decodePoints reads a JSON array of hex encoded points with the provided size, and calls decodeChunk with
+// chunks of the decoded bytes. Hex strings are dropped as soon as they're decoded. If allowEmpty is true,
+// empty strings are allowed and provided as nil.
+func (d *StreamDecoder) decodePoints(path string, pointSize int, allowEmpty bool, decodeChunk func(offset int, chunk [][]byte) error) error {
+	if err := d.expectDelim(path, '['); err != nil {
+		return err
+	}
+
+	var offset int
+	buf := make([]byte, pointsChunkSize*pointSize)
+	chunk := make([][]byte, 0, pointsChunkSize)
+	for d.decoder.More() {
+		pointPath := contribution.IndexPath(path, offset+len(chunk))
+		tok, err := d.decoder.Token()
+		if err != nil {
+			return &contribution.ValidationError{Path: pointPath, Err: fmt.Errorf("reading value: %s", err)}
+		}
+		pointHex, ok := tok.(string)
+		if !ok {
+			return &contribution.ValidationError{Path: pointPath, Err: fmt.Errorf("%w: expected a string but got %v", contribution.ErrInvalidType, tok)}
+		}
+		if pointHex == "" && allowEmpty {
+			chunk = append(chunk, nil)
+		} else {
+			pointBytes := buf[len(chunk)*pointSize : (len(chunk)+1)*pointSize]
+			if err := contribution.DecodeHex(pointPath, pointHex, pointBytes); err != nil {
+				return err
+			}
+			chunk = append(chunk, pointBytes)
+		}
+
+		if len(chunk) == pointsChunkSize {
+			if err := decodeChunk(offset, chunk); err != nil {
+				return err
+			}
+			offset += len(chunk)
+			chunk = chunk[:0]
+		}
+	}
+	if len(chunk) > 0 {
+		if err := decodeChunk(offset, chunk); err != nil {
+			return err
+		}
+	}
+
+	return d.expectDelim(path, ']')
+}
+
+// parallelDecode calls decode for each index in [0, n) using all the cores.
+func parallelDecode(n int, decode func(i int) error) error {
+	numWorkers := runtime.NumCPU()
+	if numWorkers > n {
+		numWorkers = n
+	}
+	var group errgroup.Group
+	for w := 0; w < numWorkers; w++ {
+		w := w
+		group.Go(func() error {
+			for i := w; i < n; i += numWorkers {
+				if err := decode(i); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+func (d *StreamDecoder) decodeUnknownField(path string, name string, unknownFields *map[string]json.RawMessage) error {
+	var value json.RawMessage
+	if err := d.decodeValue(contribution.JoinPath(path, name), &value); err != nil {
+		return err
+	}
+	// Compact the value, so it's encoded back as it was decoded.
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, value); err != nil {
+		return &contribution.ValidationError{Path: contribution.JoinPath(path, name), Err: fmt.Errorf("compacting value: %s", err)}
+	}
+	value = compacted.Bytes()
+	if *unknownFields == nil {
+		*unknownFields = map[string]json.RawMessage{}
+	}
+	(*unknownFields)[name] = value
+	return nil
+}
+
+// decodeValue decodes the next JSON value into v, reporting type mismatches as validation errors of path.
+func (d *StreamDecoder) decodeValue(path string, v interface{}) error {
+	if err := d.decoder.Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &contribution.ValidationError{Path: path, Err: fmt.Errorf("%w: got %s but %s was expected", contribution.ErrInvalidType, typeErr.Value, typeErr.Type)}
+		}
+		return &contribution.ValidationError{Path: path, Err: fmt.Errorf("decoding value: %s", err)}
+	}
+	return nil
+}
+
+func (d *StreamDecoder) fieldName(path string) (string, error) {
+	tok, err := d.decoder.Token()
+	if err != nil {
+		return "", &contribution.ValidationError{Path: path, Err: fmt.Errorf("reading field name: %s", err)}
+	}
+	name, ok := tok.(string)
+	if !ok {
+		return "", &contribution.ValidationError{Path: path, Err: fmt.Errorf("%w: expected a field name but got %v", contribution.ErrInvalidType, tok)}
+	}
+	return name, nil
+}
+
+func (d *StreamDecoder) expectDelim(path string, delim json.Delim) error {
+	tok, err := d.decoder.Token()
+	if err != nil {
+		return &contribution.ValidationError{Path: path, Err: fmt.Errorf("reading %s: %s", delim, err)}
+	}
+	if tok != delim {
+		return &contribution.ValidationError{Path: path, Err: fmt.Errorf("%w: expected %s but got %v", contribution.ErrInvalidType, delim, tok)}
+	}
+	return nil
+}
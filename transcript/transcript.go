@@ -1,10 +1,14 @@
 package transcript
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"runtime"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	bls12381Fr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"github.com/jsign/go-kzg-ceremony-client/contribution"
 	"golang.org/x/sync/errgroup"
 )
@@ -23,118 +27,268 @@ type Transcript struct {
 	NumG2Powers int
 	PowersOfTau contribution.PowersOfTau
 	Witness     Witness
+
+	// Unknown JSON fields of the transcript and its powers of tau, kept to be encoded back.
+	unknownFields            map[string]json.RawMessage
+	unknownPowersOfTauFields map[string]json.RawMessage
 }
 
 type Witness struct {
 	RunningProducts []bls12381.G1Affine
 	PotPubKeys      []bls12381.G2Affine
 	BLSSignatures   []*bls12381.G1Affine
+
+	unknownFields map[string]json.RawMessage
 }
 
 type BatchTranscript struct {
 	Transcripts                []Transcript
 	ParticipantIDs             []string
 	ParticipantECDSASignatures []string
+
+	unknownFields map[string]json.RawMessage
+}
+
+// NewBatchTranscript returns the initial transcript of a ceremony with the provided parameters, where all the
+// powers are the generators and the witness only contains the generators for an empty participant.
+func NewBatchTranscript(params []contribution.SubCeremonyParameters) *BatchTranscript {
+	initial := contribution.NewInitialBatchContribution(params)
+	bt := &BatchTranscript{
+		Transcripts:                make([]Transcript, len(params)),
+		ParticipantIDs:             []string{""},
+		ParticipantECDSASignatures: []string{""},
+	}
+	for i, c := range initial.Contributions {
+		bt.Transcripts[i] = Transcript{
+			NumG1Powers: c.NumG1Powers,
+			NumG2Powers: c.NumG2Powers,
+			PowersOfTau: c.PowersOfTau,
+			Witness: Witness{
+				RunningProducts: []bls12381.G1Affine{g1Generator},
+				PotPubKeys:      []bls12381.G2Affine{g2Generator},
+				BLSSignatures:   []*bls12381.G1Affine{nil},
+			},
+		}
+	}
+	return bt
 }
 
+// CurrentBatchContribution returns the current state of the ceremony as a batch contribution, which is what
+// participants receive to contribute.
+func (bt *BatchTranscript) CurrentBatchContribution() *contribution.BatchContribution {
+	bc := &contribution.BatchContribution{
+		Contributions: make([]contribution.Contribution, len(bt.Transcripts)),
+	}
+	for i, transcript := range bt.Transcripts {
+		bc.Contributions[i] = contribution.Contribution{
+			NumG1Powers: transcript.NumG1Powers,
+			NumG2Powers: transcript.NumG2Powers,
+			PowersOfTau: contribution.PowersOfTau{
+				G1Affines: append([]bls12381.G1Affine(nil), transcript.PowersOfTau.G1Affines...),
+				G2Affines: append([]bls12381.G2Affine(nil), transcript.PowersOfTau.G2Affines...),
+			},
+			PotPubKey: transcript.Witness.PotPubKeys[len(transcript.Witness.PotPubKeys)-1],
+		}
+	}
+	return bc
+}
+
+// Verify checks the transcript by folding all the pairing equations of each sub-ceremony with random scalars
+// into a single multi-pairing check, and checks that every non-empty BLS and ECDSA signature is valid. See
+// VerifyExhaustive for a check of each equation independently.
 func (bt *BatchTranscript) Verify() error {
-	// 1. `schema_check` was validated when unmarshaling the received JSON.
-	// 2.`parameter_check` was validated indirectly since the schema has the expected lengths.
-	// 3. `subgroup_checks`` was checked when parsing the JSON, since gnark-crypto does the check when decoding G(1|2) bytes.
+	// 1. `schema_check` was done when decoding the JSON, see Decode.
+	// 2. `parameter_check` against the declared parameters. Callers that know the ceremony parameters should also
+	//    call ParameterCheck with them.
+	if err := bt.ParameterCheck(bt.Parameters()); err != nil {
+		return fmt.Errorf("parameter check: %s", err)
+	}
+	// 3. `subgroup_checks` were done when decoding the points, since gnark-crypto does the check when decoding G(1|2) bytes.
+
+	for i := range bt.Transcripts {
+		if err := bt.Transcripts[i].checkRunningProductsEnds(); err != nil {
+			return fmt.Errorf("verifying %d-th transcript: %s", i, err)
+		}
+	}
 
 	var g errgroup.Group
-	g.SetLimit(runtime.NumCPU())
 	for i := range bt.Transcripts {
 		i := i
-		// 4. `tau_update_check`: check that `runningProducts` is valid by checking the pairings of each element with the
-		//    previous one ziped with potPubKeys.
-		for j := 0; j < len(bt.Transcripts[i].Witness.RunningProducts)-1; j++ {
-			j := j
-			g.Go(func() error {
-				currRunningProduct := bt.Transcripts[i].Witness.RunningProducts[j]
-				nextRunningProduct := bt.Transcripts[i].Witness.RunningProducts[j+1]
-				potPubKey := bt.Transcripts[i].Witness.PotPubKeys[j+1]
-
-				left, err := bls12381.Pair([]bls12381.G1Affine{currRunningProduct}, []bls12381.G2Affine{potPubKey})
-				if err != nil {
-					return fmt.Errorf("pairing current running product with potPubKey: %s", err)
-				}
-				right, err := bls12381.Pair([]bls12381.G1Affine{nextRunningProduct}, []bls12381.G2Affine{g2Generator})
-				if err != nil {
-					return fmt.Errorf("pairing next running product with G2: %s", err)
-				}
-
-				if !left.Equal(&right) {
-					return fmt.Errorf("pairing check failed: %s", err)
-				}
-				return nil
-			})
-		}
+		g.Go(func() error {
+			// 4, 5 and 6. `tau_update_check`, `g1PowersCheck` and `g2PowersCheck` in a single multi-pairing.
+			g1Pairs, g2Pairs, err := bt.Transcripts[i].PowersOfTau.PowersCheckPairs()
+			if err != nil {
+				return fmt.Errorf("folding powers checks of %d-th transcript: %s", i, err)
+			}
+			ok, err := bt.Transcripts[i].batchPairingCheck(g1Pairs, g2Pairs)
+			if err != nil {
+				return fmt.Errorf("batched pairing check of %d-th transcript: %s", i, err)
+			}
+			if !ok {
+				return fmt.Errorf("batched pairing check of %d-th transcript failed", i)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return fmt.Errorf("verifying sequencer transcript: %s", err)
+	}
 
-		// Check that the last running product is equal to G1 first power.
-		lastRunningProductIdx := len(bt.Transcripts[i].Witness.RunningProducts) - 1
-		lastRunningProduct := bt.Transcripts[i].Witness.RunningProducts[lastRunningProductIdx]
-		if !lastRunningProduct.Equal(&bt.Transcripts[i].PowersOfTau.G1Affines[1]) {
-			return fmt.Errorf("last running product doesn't match tau first power")
+	// BLS signatures are optional, but the present ones must be valid. See VerifyBLSSignatures for the result of
+	// each participant.
+	blsResults, err := bt.VerifyBLSSignatures()
+	if err != nil {
+		return err
+	}
+	for j, result := range blsResults {
+		for i, status := range result.SubCeremonies {
+			if status == SignatureInvalid {
+				return fmt.Errorf("the BLS signature of the %d-th participant %s in the %d-th transcript is invalid", j, result.ParticipantID, i)
+			}
 		}
+	}
 
-		// Check that the first running product is the tau^0 power.
-		firstRunningProduct := bt.Transcripts[i].Witness.RunningProducts[0]
-		if !firstRunningProduct.Equal(&bt.Transcripts[i].PowersOfTau.G1Affines[0]) {
-			return fmt.Errorf("the first running product element should match")
+	// The same for the ECDSA signatures, see VerifyECDSASignatures.
+	ecdsaResults, err := bt.VerifyECDSASignatures()
+	if err != nil {
+		return err
+	}
+	for j, result := range ecdsaResults {
+		if result.Status == SignatureInvalid {
+			return fmt.Errorf("the ECDSA signature of the %d-th participant %s is invalid", j, result.ParticipantID)
 		}
+	}
 
-		// 5. `g1PowersCheck`: checks that the G1 powers in the transcript are coherent powers.
-		for j := 0; j < len(bt.Transcripts[i].PowersOfTau.G1Affines)-1; j++ {
-			j := j
-			g.Go(func() error {
-				baseTauG2 := bt.Transcripts[i].PowersOfTau.G2Affines[1]
-				currG1 := bt.Transcripts[i].PowersOfTau.G1Affines[j]
-				nextG1 := bt.Transcripts[i].PowersOfTau.G1Affines[j+1]
-
-				left, err := bls12381.Pair([]bls12381.G1Affine{currG1}, []bls12381.G2Affine{baseTauG2})
-				if err != nil {
-					return fmt.Errorf("pairing current G1 with baseTauG2: %s", err)
-				}
-				right, err := bls12381.Pair([]bls12381.G1Affine{nextG1}, []bls12381.G2Affine{g2Generator})
-				if err != nil {
-					return fmt.Errorf("pairing next G1 with G2: %s", err)
-				}
-
-				if !left.Equal(&right) {
-					return fmt.Errorf("pairing check failed: %s", err)
-				}
-				return nil
-			})
+	return nil
+}
+
+// batchPairingCheck folds the `tau_update_check` equations e(RP[j], PK[j+1]) == e(RP[j+1], g2) with random scalars
+// together with the provided pairs (e.g: the powers checks of contribution.PowersCheckPairs), and checks all of
+// them with a single multi-pairing. The Miller loops are split in chunks calculated in parallel, and a single
+// final exponentiation is done for all of them.
+func (t *Transcript) batchPairingCheck(g1Pairs []bls12381.G1Affine, g2Pairs []bls12381.G2Affine) (bool, error) {
+	// Π_j e(t_j·RP[j], PK[j+1]) · e(-Σ_j t_j·RP[j+1], g2) == 1
+	numUpdates := len(t.Witness.RunningProducts) - 1
+	runningProductsG1 := make([]bls12381.G1Affine, numUpdates)
+	scalars := make([]bls12381Fr.Element, numUpdates)
+	for j := range scalars {
+		if _, err := scalars[j].SetRandom(); err != nil {
+			return false, fmt.Errorf("get random Fr: %s", err)
+		}
+	}
+	if numUpdates > 0 {
+		var nextRunningProducts bls12381.G1Affine
+		if _, err := nextRunningProducts.MultiExp(t.Witness.RunningProducts[1:], scalars, ecc.MultiExpConfig{}); err != nil {
+			return false, fmt.Errorf("calculating running products combination: %s", err)
 		}
+		nextRunningProducts.Neg(&nextRunningProducts)
+		g1Pairs = append(g1Pairs, nextRunningProducts)
+		g2Pairs = append(g2Pairs, g2Generator)
+	}
 
-		// 6. `g2PowersCheck`: checks that the G2 powers in the transcript are coherent powers.
-		for j := 0; j < len(bt.Transcripts[i].PowersOfTau.G2Affines)-1; j++ {
-			j := j
-			g.Go(func() error {
-				baseTauG1 := bt.Transcripts[i].PowersOfTau.G1Affines[1]
-				currG2 := bt.Transcripts[i].PowersOfTau.G2Affines[j]
-				nextG2 := bt.Transcripts[i].PowersOfTau.G2Affines[j+1]
-
-				left, err := bls12381.Pair([]bls12381.G1Affine{baseTauG1}, []bls12381.G2Affine{currG2})
-				if err != nil {
-					return fmt.Errorf("pairing baseTauG1 with current G2: %s", err)
-				}
-				right, err := bls12381.Pair([]bls12381.G1Affine{g1Generator}, []bls12381.G2Affine{nextG2})
-				if err != nil {
-					return fmt.Errorf("pairing G1 with next G2: %s", err)
-				}
-
-				if !left.Equal(&right) {
-					return fmt.Errorf("pairing check failed: %s", err)
-				}
+	numChunks := runtime.NumCPU()
+	chunkSize := (numUpdates + numChunks - 1) / numChunks
+	millerLoops := make([]bls12381.GT, numChunks+1)
+	var g errgroup.Group
+	for c := 0; c < numChunks; c++ {
+		start, end := c*chunkSize, (c+1)*chunkSize
+		if end > numUpdates {
+			end = numUpdates
+		}
+		c := c
+		g.Go(func() error {
+			millerLoops[c].SetOne()
+			if start >= end {
 				return nil
-			})
+			}
+			for j := start; j < end; j++ {
+				var scalar big.Int
+				scalars[j].BigInt(&scalar)
+				runningProductsG1[j].ScalarMultiplication(&t.Witness.RunningProducts[j], &scalar)
+			}
+			ml, err := bls12381.MillerLoop(runningProductsG1[start:end], t.Witness.PotPubKeys[start+1:end+1])
+			if err != nil {
+				return fmt.Errorf("miller loop of running products: %s", err)
+			}
+			millerLoops[c] = ml
+			return nil
+		})
+	}
+	g.Go(func() error {
+		millerLoops[numChunks].SetOne()
+		if len(g1Pairs) == 0 {
+			return nil
+		}
+		ml, err := bls12381.MillerLoop(g1Pairs, g2Pairs)
+		if err != nil {
+			return fmt.Errorf("miller loop of powers checks: %s", err)
 		}
+		millerLoops[numChunks] = ml
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return false, err
 	}
 
-	if err := g.Wait(); err != nil {
-		return fmt.Errorf("verifying sequencer transcript: %s", err)
+	others := make([]*bls12381.GT, len(millerLoops)-1)
+	for i := range others {
+		others[i] = &millerLoops[i+1]
+	}
+	result := bls12381.FinalExponentiation(&millerLoops[0], others...)
+
+	return result.IsOne(), nil
+}
+
+// checkRunningProductsEnds checks that the running products start at the generator and end at the current
+// tau first power.
+func (t *Transcript) checkRunningProductsEnds() error {
+	if len(t.Witness.RunningProducts) == 0 || len(t.Witness.PotPubKeys) != len(t.Witness.RunningProducts) {
+		return fmt.Errorf("there're %d running products and %d potPubKeys", len(t.Witness.RunningProducts), len(t.Witness.PotPubKeys))
+	}
+	if len(t.PowersOfTau.G1Affines) < 2 {
+		return fmt.Errorf("at least two G1 powers are needed")
+	}
+
+	// Check that the last running product is equal to G1 first power.
+	lastRunningProduct := t.Witness.RunningProducts[len(t.Witness.RunningProducts)-1]
+	if !lastRunningProduct.Equal(&t.PowersOfTau.G1Affines[1]) {
+		return fmt.Errorf("last running product doesn't match tau first power")
+	}
+
+	// Check that the first running product is the tau^0 power.
+	firstRunningProduct := t.Witness.RunningProducts[0]
+	if !firstRunningProduct.Equal(&t.PowersOfTau.G1Affines[0]) {
+		return fmt.Errorf("the first running product element should match")
+	}
+
+	return nil
+}
+
+// checkTauUpdate checks the `tau_update_check` equation e(prevRunningProduct, potPubKey) == e(runningProduct, g2).
+func checkTauUpdate(prevRunningProduct *bls12381.G1Affine, potPubKey *bls12381.G2Affine, runningProduct *bls12381.G1Affine) (bool, error) {
+	var negRunningProduct bls12381.G1Affine
+	negRunningProduct.Neg(runningProduct)
+	return bls12381.PairingCheck([]bls12381.G1Affine{*prevRunningProduct, negRunningProduct}, []bls12381.G2Affine{*potPubKey, g2Generator})
+}
+
+// VerifyExhaustive checks the transcript checking each pairing equation independently, which is much slower than
+// Verify but doesn't rely on random linear combinations. See VerifyReport for a report of all the failures.
+func (bt *BatchTranscript) VerifyExhaustive() error {
+	// 1. `schema_check` was done when decoding the JSON, see Decode.
+	// 2. `parameter_check` against the declared parameters. Callers that know the ceremony parameters should also
+	//    call ParameterCheck with them.
+	if err := bt.ParameterCheck(bt.Parameters()); err != nil {
+		return fmt.Errorf("parameter check: %s", err)
+	}
+	// 3. `subgroup_checks` were done when decoding the points, since gnark-crypto does the check when decoding G(1|2) bytes.
+
+	// 4, 5 and 6. `tau_update_check`, `g1PowersCheck` and `g2PowersCheck` checking each equation.
+	for i := range bt.Transcripts {
+		for _, result := range bt.powersChecks(i, true) {
+			if !result.Passed {
+				return fmt.Errorf("verifying sequencer transcript: %s", result.err())
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,877 @@
+package transcript
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+	"github.com/jsign/go-kzg-ceremony-client/eip712"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	verifiers := map[string]func(bt *BatchTranscript) error{
+		"batched":    (*BatchTranscript).Verify,
+		"exhaustive": (*BatchTranscript).VerifyExhaustive,
+	}
+	tampers := map[string]func(bt *BatchTranscript){
+		"g1 power": func(bt *BatchTranscript) {
+			bt.Transcripts[1].PowersOfTau.G1Affines[3] = bt.Transcripts[1].PowersOfTau.G1Affines[2]
+		},
+		"g2 power": func(bt *BatchTranscript) {
+			bt.Transcripts[2].PowersOfTau.G2Affines[4] = bt.Transcripts[2].PowersOfTau.G2Affines[3]
+		},
+		"running product": func(bt *BatchTranscript) {
+			bt.Transcripts[3].Witness.RunningProducts[1] = bt.Transcripts[3].Witness.RunningProducts[2]
+		},
+		"potPubKey": func(bt *BatchTranscript) {
+			bt.Transcripts[0].Witness.PotPubKeys[2] = bt.Transcripts[0].Witness.PotPubKeys[1]
+		},
+		"last running product": func(bt *BatchTranscript) {
+			bt.Transcripts[0].Witness.RunningProducts = bt.Transcripts[0].Witness.RunningProducts[:2]
+			bt.Transcripts[0].Witness.PotPubKeys = bt.Transcripts[0].Witness.PotPubKeys[:2]
+		},
+	}
+
+	for verifierName, verify := range verifiers {
+		verifierName, verify := verifierName, verify
+		t.Run(verifierName, func(t *testing.T) {
+			t.Parallel()
+
+			bt := newTestBatchTranscript(t, "git|6136245|jsign", "eth|0x5afe36d82de8990b777f82651b96608ec54d190d")
+			require.NoError(t, verify(bt))
+
+			for tamperName, tamper := range tampers {
+				bt := newTestBatchTranscript(t, "git|6136245|jsign", "eth|0x5afe36d82de8990b777f82651b96608ec54d190d")
+				tamper(bt)
+				require.Error(t, verify(bt), tamperName)
+			}
+		})
+	}
+
+	// A transcript without contributions is valid.
+	bt := newTestBatchTranscript(t)
+	require.NoError(t, bt.Verify())
+	require.NoError(t, bt.VerifyExhaustive())
+}
+
+func TestVerifyReport(t *testing.T) {
+	t.Parallel()
+
+	identities := []string{"git|6136245|jsign", "eth|0x5afe36d82de8990b777f82651b96608ec54d190d"}
+	for _, exhaustive := range []bool{false, true} {
+		exhaustive := exhaustive
+		t.Run(fmt.Sprintf("exhaustive=%v", exhaustive), func(t *testing.T) {
+			t.Parallel()
+
+			bt := newTestBatchTranscript(t, identities...)
+			report := bt.VerifyReport(VerifyOptions{Exhaustive: exhaustive, BLSSignatures: true})
+			require.True(t, report.Passed())
+			require.NoError(t, report.Err())
+			// Parameters, three powers checks per sub-ceremony, and the signatures.
+			require.Len(t, report.Checks, 1+3*len(bt.Transcripts)+2)
+			for _, result := range report.Checks {
+				require.True(t, result.Passed, result.Check)
+				require.Empty(t, result.Failures)
+			}
+			require.Equal(t, CheckTauUpdate, report.Checks[1].Check)
+			require.Equal(t, 0, report.Checks[1].SubCeremony)
+			require.Equal(t, len(identities), report.Checks[1].Checked)
+			// The initial participant and the test participants don't have ECDSA signatures.
+			require.Equal(t, CheckECDSASignatures, report.Checks[len(report.Checks)-2].Check)
+			require.Equal(t, &SignatureCounts{Absent: 3}, report.Checks[len(report.Checks)-2].Signatures)
+			require.Equal(t, CheckBLSSignatures, report.Checks[len(report.Checks)-1].Check)
+			require.Equal(t, &SignatureCounts{Valid: 2 * len(bt.Transcripts), Absent: len(bt.Transcripts)}, report.Checks[len(report.Checks)-1].Signatures)
+
+			bt = newTestBatchTranscript(t, identities...)
+			bt.Transcripts[1].PowersOfTau.G1Affines[3] = bt.Transcripts[1].PowersOfTau.G1Affines[2]
+			bt.Transcripts[0].Witness.PotPubKeys[1] = bt.Transcripts[0].Witness.PotPubKeys[2]
+			report = bt.VerifyReport(VerifyOptions{Exhaustive: exhaustive})
+			require.False(t, report.Passed())
+			var failed []CheckResult
+			for _, result := range report.Checks {
+				if !result.Passed {
+					failed = append(failed, result)
+				}
+			}
+			require.Len(t, failed, 2)
+
+			require.Equal(t, CheckTauUpdate, failed[0].Check)
+			require.Equal(t, 0, failed[0].SubCeremony)
+			require.Equal(t, []CheckFailure{{
+				Index:       1,
+				Participant: identities[0],
+				Path:        "transcripts[0].witness.runningProducts[1]",
+				Message:     "the running product of the 1-th participant isn't an update with its potPubKey",
+			}}, failed[0].Failures)
+
+			// The tampered power breaks the equations with the previous and next powers.
+			require.Equal(t, CheckG1Powers, failed[1].Check)
+			require.Equal(t, 1, failed[1].SubCeremony)
+			require.Len(t, failed[1].Failures, 2)
+			require.Equal(t, 3, failed[1].Failures[0].Index)
+			require.Equal(t, 4, failed[1].Failures[1].Index)
+			require.Equal(t, identities[1], failed[1].Failures[0].Participant)
+			require.Equal(t, "transcripts[1].powersOfTau.G1Powers[3]", failed[1].Failures[0].Path)
+
+			require.EqualError(t, report.Err(), "tau_update check of 0-th transcript failed with 1 failures, the first one: the running product of the 1-th participant isn't an update with its potPubKey")
+			encoded, err := json.Marshal(report)
+			require.NoError(t, err)
+			var decoded VerifyReport
+			require.NoError(t, json.Unmarshal(encoded, &decoded))
+			require.Equal(t, *report, decoded)
+		})
+	}
+
+	t.Run("parameters", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newTestBatchTranscript(t, identities...)
+		report := bt.VerifyReport(VerifyOptions{Parameters: contribution.CeremonyParameters})
+		require.Len(t, report.Checks, 1)
+		require.Equal(t, CheckParameters, report.Checks[0].Check)
+		require.False(t, report.Checks[0].Passed)
+		require.Equal(t, "transcripts[0].numG1Powers", report.Checks[0].Failures[0].Path)
+	})
+
+	t.Run("decode", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newTestBatchTranscriptWithPowers(t, [][2]int{{4, 3}}, identities...)
+		results := DecodeCheckResults(bt, nil, time.Second)
+		require.Len(t, results, 2)
+		require.True(t, results[0].Passed && results[1].Passed)
+		// Powers, running products, potPubKeys and BLS signatures.
+		require.Equal(t, 4+3+3+3+2, results[0].Checked)
+
+		err := &contribution.ValidationError{Path: "transcripts[1].powersOfTau.G2Powers[2]", Err: contribution.ErrInvalidPoint}
+		results = DecodeCheckResults(nil, err, time.Second)
+		require.Equal(t, []CheckResult{{
+			Check:       CheckSubgroup,
+			SubCeremony: -1,
+			Failures:    []CheckFailure{{Index: -1, Path: err.Path, Message: err.Error()}},
+			Duration:    time.Second,
+		}}, results)
+	})
+}
+
+func TestVerifyBLSSignatures(t *testing.T) {
+	t.Parallel()
+
+	bt := newTestBatchTranscript(t, "git|6136245|jsign", "", "eth|0x5afe36d82de8990b777f82651b96608ec54d190d")
+	// Absent signatures are fine.
+	require.NoError(t, bt.Verify())
+
+	// Sign the third contribution with an identity that isn't the recorded participant id.
+	bt.ParticipantIDs[3] = "eth|0x0000000000000000000000000000000000000001"
+
+	results, err := bt.VerifyBLSSignatures()
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	// The first element is the initial state of the ceremony, which isn't signed.
+	require.Equal(t, SignatureAbsent, results[0].Status)
+	require.Equal(t, SignatureValid, results[1].Status)
+	require.Equal(t, SignatureAbsent, results[2].Status)
+	require.Equal(t, SignatureInvalid, results[3].Status)
+	for _, status := range results[1].SubCeremonies {
+		require.Equal(t, SignatureValid, status)
+	}
+	require.ErrorContains(t, bt.Verify(), "BLS signature of the 3-th participant")
+
+	// A single tampered sub-ceremony signature makes the participant signature invalid.
+	bt.ParticipantIDs[3] = "eth|0x5afe36d82de8990b777f82651b96608ec54d190d"
+	bt.Transcripts[2].Witness.BLSSignatures[3] = bt.Transcripts[1].Witness.BLSSignatures[3]
+	results, err = bt.VerifyBLSSignatures()
+	require.NoError(t, err)
+	require.Equal(t, SignatureInvalid, results[3].Status)
+	require.Equal(t, SignatureValid, results[3].SubCeremonies[1])
+	require.Equal(t, SignatureInvalid, results[3].SubCeremonies[2])
+	require.EqualError(t, bt.Verify(), "the BLS signature of the 3-th participant eth|0x5afe36d82de8990b777f82651b96608ec54d190d in the 2-th transcript is invalid")
+}
+
+func TestVerifyECDSASignatures(t *testing.T) {
+	t.Parallel()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	identity := "eth|" + strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	otherKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	otherIdentity := "eth|" + strings.ToLower(crypto.PubkeyToAddress(otherKey.PublicKey).Hex())
+
+	bt := newTestBatchTranscript(t, identity, otherIdentity, "git|6136245|jsign", identity)
+
+	sign := func(key *ecdsa.PrivateKey, participantIdx int) string {
+		hash, err := eip712.Hash(bt.PotPubKeys(participantIdx))
+		require.NoError(t, err)
+		signature, err := crypto.Sign(hash, key)
+		require.NoError(t, err)
+		signature[crypto.RecoveryIDOffset] += 27
+		return "0x" + hex.EncodeToString(signature)
+	}
+	bt.ParticipantECDSASignatures[1] = sign(key, 1)
+	bt.ParticipantECDSASignatures[2] = sign(key, 2)
+	bt.ParticipantECDSASignatures[3] = sign(key, 3)
+
+	results, err := bt.VerifyECDSASignatures()
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+	require.Equal(t, SignatureAbsent, results[0].Status)
+	require.Equal(t, SignatureValid, results[1].Status)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), results[1].Signer)
+	// Signed by a key that doesn't match the participant identity.
+	require.Equal(t, SignatureInvalid, results[2].Status)
+	// Non-Ethereum participants can't have ECDSA signatures.
+	require.Equal(t, SignatureInvalid, results[3].Status)
+	require.Equal(t, SignatureAbsent, results[4].Status)
+
+	require.EqualError(t, bt.Verify(), fmt.Sprintf("the ECDSA signature of the 2-th participant %s is invalid", otherIdentity))
+	report := bt.VerifyReport(VerifyOptions{})
+	ecdsaResult := report.Checks[len(report.Checks)-1]
+	require.Equal(t, CheckECDSASignatures, ecdsaResult.Check)
+	require.Equal(t, &SignatureCounts{Valid: 1, Invalid: 2, Absent: 2}, ecdsaResult.Signatures)
+	require.Len(t, ecdsaResult.Failures, 2)
+
+	// Absent signatures are fine.
+	bt.ParticipantECDSASignatures[2], bt.ParticipantECDSASignatures[3] = "", ""
+	require.NoError(t, bt.Verify())
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	bt := newTestBatchTranscript(t)
+	require.NoError(t, bt.Verify())
+	for i := 1; i <= 5; i++ {
+		bc := bt.CurrentBatchContribution()
+		require.NoError(t, bc.Contribute(""))
+		require.NoError(t, bt.Apply("", bc))
+		require.NoError(t, bt.Verify())
+		require.Len(t, bt.ParticipantIDs, i+1)
+		require.Len(t, bt.ParticipantECDSASignatures, i+1)
+		for _, transcript := range bt.Transcripts {
+			require.Len(t, transcript.Witness.RunningProducts, i+1)
+			require.Len(t, transcript.Witness.PotPubKeys, i+1)
+			require.Len(t, transcript.Witness.BLSSignatures, i+1)
+		}
+	}
+
+	t.Run("signatures", func(t *testing.T) {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		identity := "eth|0x" + hex.EncodeToString(crypto.PubkeyToAddress(key.PublicKey).Bytes())
+
+		bt := newTestBatchTranscript(t)
+		bc := bt.CurrentBatchContribution()
+		require.NoError(t, bc.Contribute(identity))
+		require.NoError(t, eip712.SignBatchContribution(key, bc))
+		signatures := Signatures{ECDSA: bc.ECDSASignature, BLS: make([]*bls12381.G1Affine, len(bc.Contributions))}
+		for i := range bc.Contributions {
+			signatures.BLS[i] = bc.Contributions[i].BLSSignature
+		}
+		require.NoError(t, bt.Apply(identity, bc, signatures))
+		require.Equal(t, []string{"", identity}, bt.ParticipantIDs)
+
+		blsResults, err := bt.VerifyBLSSignatures()
+		require.NoError(t, err)
+		require.Equal(t, SignatureValid, blsResults[1].Status)
+		ecdsaResults, err := bt.VerifyECDSASignatures()
+		require.NoError(t, err)
+		require.Equal(t, SignatureValid, ecdsaResults[1].Status)
+
+		require.Error(t, bt.Apply("", bt.CurrentBatchContribution(), Signatures{BLS: signatures.BLS[1:]}))
+		require.Error(t, bt.Apply("", bt.CurrentBatchContribution(), Signatures{}, Signatures{}))
+	})
+
+	t.Run("invalid contribution", func(t *testing.T) {
+		bt := newTestBatchTranscript(t, "")
+		bc := bt.CurrentBatchContribution()
+		require.NoError(t, bc.Contribute(""))
+		bc.Contributions[2].PowersOfTau.G2Affines[3] = bc.Contributions[2].PowersOfTau.G2Affines[2]
+		require.Error(t, bt.Apply("", bc))
+
+		bc = bt.CurrentBatchContribution()
+		require.NoError(t, bc.Contribute(""))
+		bc.Contributions = bc.Contributions[1:]
+		require.Error(t, bt.Apply("", bc))
+
+		// The transcript isn't modified by failed applies.
+		require.Len(t, bt.ParticipantIDs, 2)
+		require.NoError(t, bt.Verify())
+	})
+
+	t.Run("copies the contribution", func(t *testing.T) {
+		bt := newTestBatchTranscript(t)
+		bc := bt.CurrentBatchContribution()
+		require.NoError(t, bc.Contribute("git|1|alice"))
+		signatures := Signatures{BLS: []*bls12381.G1Affine{bc.Contributions[0].BLSSignature, nil, nil, nil}}
+		require.NoError(t, bt.Apply("git|1|alice", bc, signatures))
+
+		// Contributing again on the same batch doesn't change the transcript.
+		require.NoError(t, bc.Contribute(""))
+		*signatures.BLS[0] = bls12381.G1Affine{}
+		require.NoError(t, bt.Verify())
+	})
+
+	t.Run("inconsistent witness", func(t *testing.T) {
+		bt := newTestBatchTranscript(t, "")
+		bt.Transcripts[1].Witness.BLSSignatures = bt.Transcripts[1].Witness.BLSSignatures[:1]
+		bc := bt.CurrentBatchContribution()
+		require.NoError(t, bc.Contribute(""))
+		require.EqualError(t, bt.Apply("", bc), "1-th transcript witness has 2 running products, 2 potPubKeys and 1 bls signatures but there're 2 participant ids")
+
+		bt = newTestBatchTranscript(t, "")
+		bt.ParticipantECDSASignatures = bt.ParticipantECDSASignatures[:1]
+		require.Error(t, bt.Apply("", bc))
+	})
+}
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+
+	official, err := os.ReadFile("testdata/transcript.json")
+	require.NoError(t, err)
+	bt, err := Decode(bytes.NewReader(official))
+	require.NoError(t, err)
+	require.NoError(t, bt.Verify())
+	// Participants that didn't BLS sign have empty signatures.
+	require.Nil(t, bt.Transcripts[0].Witness.BLSSignatures[1])
+
+	t.Run("pretty", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Encode(&buf, bt, true))
+		require.Equal(t, string(official), buf.String())
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		var compact bytes.Buffer
+		require.NoError(t, json.Compact(&compact, official))
+		var buf bytes.Buffer
+		require.NoError(t, Encode(&buf, bt, false))
+		require.Equal(t, compact.String(), buf.String())
+	})
+
+	t.Run("unknown fields", func(t *testing.T) {
+		withUnknown := strings.Replace(string(official), `"participantEcdsaSignatures": [`, `"sequencerVersion": "1.0", "participantEcdsaSignatures": [`, 1)
+		withUnknown = strings.Replace(withUnknown, `"witness": {`, `"witness": {"zFutureField": {"a": [1, 2]},`, 1)
+		withUnknown = strings.Replace(withUnknown, `"G1Powers": [`, `"note": null, "G1Powers": [`, 1)
+		bt, err := Decode(strings.NewReader(withUnknown))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, Encode(&buf, bt, false))
+		encoded := buf.String()
+		require.Contains(t, encoded, `,"sequencerVersion":"1.0"}`)
+		require.Contains(t, encoded, `,"zFutureField":{"a":[1,2]}}`)
+		require.Contains(t, encoded, `,"note":null}`)
+
+		// Encoding the decoded transcript again is stable.
+		bt, err = Decode(strings.NewReader(encoded))
+		require.NoError(t, err)
+		buf.Reset()
+		require.NoError(t, Encode(&buf, bt, false))
+		require.Equal(t, encoded, buf.String())
+	})
+}
+
+func TestBinary(t *testing.T) {
+	t.Parallel()
+
+	official, err := os.ReadFile("testdata/transcript.json")
+	require.NoError(t, err)
+	officialBt, err := Decode(bytes.NewReader(official))
+	require.NoError(t, err)
+	bt := newTestBatchTranscript(t, "git|1|alice", "")
+	bt.ParticipantECDSASignatures[1] = "0x" + strings.Repeat("ab", 65)
+
+	for _, bt := range []*BatchTranscript{officialBt, bt} {
+		for _, uncompressed := range []bool{false, true} {
+			encoded, err := EncodeBinary(bt, uncompressed)
+			require.NoError(t, err)
+			require.True(t, IsBinary(encoded))
+			decoded, err := DecodeBinary(encoded)
+			require.NoError(t, err)
+			require.Equal(t, bt, decoded)
+		}
+	}
+
+	encoded, err := EncodeBinary(officialBt, false)
+	require.NoError(t, err)
+	require.False(t, IsBinary(official))
+	require.Less(t, len(encoded), len(official)/2)
+
+	// withChecksum fixes the checksum after tampering the content, so the decoder checks are reached.
+	withChecksum := func(b []byte) []byte {
+		checksum := sha256.Sum256(b[:len(b)-sha256.Size])
+		copy(b[len(b)-sha256.Size:], checksum[:])
+		return b
+	}
+	g1Bytes := officialBt.Transcripts[0].PowersOfTau.G1Affines[1].Bytes()
+	g1Offset := bytes.Index(encoded, g1Bytes[:])
+	require.Positive(t, g1Offset)
+	tests := []struct {
+		name   string
+		tamper func(b []byte) []byte
+	}{
+		{name: "corrupted", tamper: func(b []byte) []byte { b[len(b)/2] ^= 1; return b }},
+		{name: "truncated", tamper: func(b []byte) []byte { return withChecksum(append(b[:len(b)/2], b[len(b)-sha256.Size:]...)) }},
+		{name: "too short", tamper: func(b []byte) []byte { return b[:binaryHeaderSize] }},
+		{name: "unknown version", tamper: func(b []byte) []byte { b[len(binaryMagic)] = 2; return withChecksum(b) }},
+		{name: "unknown flags", tamper: func(b []byte) []byte { b[len(binaryMagic)+1] |= 1 << 7; return withChecksum(b) }},
+		{name: "too many participants", tamper: func(b []byte) []byte { b[len(binaryMagic)+6] = 0xff; return withChecksum(b) }},
+		{name: "not in subgroup", tamper: func(b []byte) []byte {
+			var p bls12381.G1Affine
+			p.X.SetOne()
+			p.Y.SetOne()
+			pBytes := p.Bytes()
+			copy(b[g1Offset:], pBytes[:])
+			return withChecksum(b)
+		}},
+		{name: "mixed compression", tamper: func(b []byte) []byte { b[len(binaryMagic)+1] |= binaryFlagUncompressed; return withChecksum(b) }},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			tampered := test.tamper(append([]byte(nil), encoded...))
+			_, err := DecodeBinary(tampered)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestStreamDecoder(t *testing.T) {
+	t.Parallel()
+
+	bt := newTestBatchTranscript(t, "git|1|alice", "")
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, bt, false))
+	encoded := buf.Bytes()
+
+	t.Run("one at a time", func(t *testing.T) {
+		reader := &countingReader{r: bytes.NewReader(encoded)}
+		decoder := NewStreamDecoder(reader)
+		for i := range bt.Transcripts {
+			transcript, err := decoder.Next()
+			require.NoError(t, err)
+			require.Equal(t, bt.Transcripts[i], *transcript)
+			// Only the decoded sub-transcripts were read, and not the whole stream.
+			if i == 0 {
+				require.Less(t, reader.n, len(encoded)/2)
+			}
+		}
+		_, err := decoder.Next()
+		require.ErrorIs(t, err, io.EOF)
+		decoded, err := decoder.Finish()
+		require.NoError(t, err)
+		require.Empty(t, decoded.Transcripts)
+		require.Equal(t, bt.ParticipantIDs, decoded.ParticipantIDs)
+		require.Equal(t, bt.ParticipantECDSASignatures, decoded.ParticipantECDSASignatures)
+	})
+
+	t.Run("fields order", func(t *testing.T) {
+		var fields map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(encoded, &fields))
+		reordered := fmt.Sprintf(`{"participantEcdsaSignatures":%s,"participantIds":%s,"transcripts":%s}`,
+			fields["participantEcdsaSignatures"], fields["participantIds"], fields["transcripts"])
+		decoded, err := Decode(strings.NewReader(reordered))
+		require.NoError(t, err)
+		require.Equal(t, bt, decoded)
+	})
+
+	t.Run("unconsumed transcripts", func(t *testing.T) {
+		decoder := NewStreamDecoder(bytes.NewReader(encoded))
+		_, err := decoder.Next()
+		require.NoError(t, err)
+		_, err = decoder.Finish()
+		require.Error(t, err)
+	})
+
+	t.Run("invalid points", func(t *testing.T) {
+		g1Hex := g1PointJSON(bt.Transcripts[0].PowersOfTau.G1Affines[1])
+		tests := []struct {
+			name    string
+			replace string
+			err     error
+		}{
+			{name: "missing 0x prefix", replace: `"` + g1Hex[3:] + `"`, err: contribution.ErrMissingHexPrefix},
+			{name: "short", replace: g1Hex[:20] + `"`, err: contribution.ErrInvalidHex},
+			{name: "invalid hex", replace: `"0x` + strings.Repeat("zz", 48) + `"`, err: contribution.ErrInvalidHex},
+			{name: "not a point", replace: `"0x` + strings.Repeat("ab", 48) + `"`, err: contribution.ErrInvalidPoint},
+			{name: "not a string", replace: `42`, err: contribution.ErrInvalidType},
+			{name: "empty power", replace: `""`, err: contribution.ErrMissingHexPrefix},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.name, func(t *testing.T) {
+				t.Parallel()
+				invalid := strings.Replace(string(encoded), g1Hex, test.replace, 1)
+				_, err := Decode(strings.NewReader(invalid))
+				require.ErrorIs(t, err, test.err)
+				require.ErrorContains(t, err, "transcripts[0].powersOfTau.G1Powers[1]: ")
+			})
+		}
+	})
+
+	t.Run("invalid witness", func(t *testing.T) {
+		invalid := strings.Replace(string(encoded), `"potPubkeys":[`, `"potPubkeys":[4,`, 1)
+		_, err := Decode(strings.NewReader(invalid))
+		require.ErrorIs(t, err, contribution.ErrInvalidType)
+		require.ErrorContains(t, err, "transcripts[0].witness.potPubkeys[0]: ")
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		invalid := strings.Replace(string(encoded), `"numG1Powers":`, `"numG1Powers":"`, 1)
+		invalid = strings.Replace(invalid, `,"numG2Powers"`, `","numG2Powers"`, 1)
+		_, err := Decode(strings.NewReader(invalid))
+		require.ErrorIs(t, err, contribution.ErrInvalidType)
+		require.ErrorContains(t, err, "transcripts[0].numG1Powers: ")
+	})
+}
+
+func TestCheckInclusion(t *testing.T) {
+	t.Parallel()
+
+	bt := newTestBatchTranscript(t, "git|1|alice")
+	bc := bt.CurrentBatchContribution()
+	require.NoError(t, bc.Contribute("eth|0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	require.NoError(t, bt.Apply("eth|0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", bc))
+	bc2 := bt.CurrentBatchContribution()
+	require.NoError(t, bc2.Contribute(""))
+	require.NoError(t, bt.Apply("git|2|bob", bc2))
+
+	t.Run("included", func(t *testing.T) {
+		t.Parallel()
+		inclusion, err := bt.CheckInclusion(bc, "eth|0xAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+		require.NoError(t, err)
+		require.Equal(t, 2, inclusion.Index)
+		require.Equal(t, "eth|0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", inclusion.ParticipantID)
+		for i, transcript := range bt.Transcripts {
+			require.True(t, inclusion.PreviousRunningProducts[i].Equal(&transcript.Witness.RunningProducts[1]))
+			require.True(t, inclusion.RunningProducts[i].Equal(&bc.Contributions[i].PowersOfTau.G1Affines[1]))
+		}
+
+		// Without an expected participant id, the one in the transcript is returned.
+		inclusion, err = bt.CheckInclusion(bc2, "")
+		require.NoError(t, err)
+		require.Equal(t, 3, inclusion.Index)
+		require.Equal(t, "git|2|bob", inclusion.ParticipantID)
+	})
+	t.Run("other participant", func(t *testing.T) {
+		t.Parallel()
+		_, err := bt.CheckInclusion(bc, "git|2|bob")
+		require.EqualError(t, err, "the contribution at index 2 belongs to eth|0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa instead of git|2|bob")
+	})
+	t.Run("not included", func(t *testing.T) {
+		t.Parallel()
+		notIncluded := bt.CurrentBatchContribution()
+		require.NoError(t, notIncluded.Contribute(""))
+		_, err := bt.CheckInclusion(notIncluded, "")
+		require.ErrorIs(t, err, ErrNotIncluded)
+
+		// The initial state isn't a contribution.
+		_, err = bt.CheckInclusion(NewBatchTranscript(bt.Parameters()).CurrentBatchContribution(), "")
+		require.ErrorIs(t, err, ErrNotIncluded)
+	})
+	t.Run("mixed sub-ceremonies", func(t *testing.T) {
+		t.Parallel()
+		mixed := *bc
+		mixed.Contributions = append([]contribution.Contribution(nil), bc.Contributions...)
+		mixed.Contributions[1] = bc2.Contributions[1]
+		_, err := bt.CheckInclusion(&mixed, "")
+		require.ErrorIs(t, err, ErrNotIncluded)
+	})
+	t.Run("tampered running product", func(t *testing.T) {
+		t.Parallel()
+		tampered := newTestBatchTranscript(t, "git|1|alice")
+		tamperedBC := tampered.CurrentBatchContribution()
+		require.NoError(t, tamperedBC.Contribute(""))
+		require.NoError(t, tampered.Apply("git|2|bob", tamperedBC))
+		// A running product that isn't an update of the previous one with the potPubKey.
+		tamperedBC.Contributions[3].PowersOfTau.G1Affines[1] = tampered.Transcripts[3].Witness.RunningProducts[1]
+		tampered.Transcripts[3].Witness.RunningProducts[2] = tampered.Transcripts[3].Witness.RunningProducts[1]
+		_, err := tampered.CheckInclusion(tamperedBC, "git|2|bob")
+		require.EqualError(t, err, "the running product of the 3-th transcript at index 2 isn't an update with the contribution secret")
+
+		// A running product that isn't the contribution tau first power.
+		tamperedBC.Contributions[3].PowersOfTau.G1Affines[1] = g1Generator
+		_, err = tampered.CheckInclusion(tamperedBC, "")
+		require.EqualError(t, err, "the running product of the 3-th transcript at index 2 isn't the contribution tau first power")
+	})
+}
+
+func TestInclusionProof(t *testing.T) {
+	t.Parallel()
+
+	bt := newTestBatchTranscriptWithPowers(t, [][2]int{{4, 3}, {8, 3}}, "git|1|alice", "", "git|3|carol", "git|4|dave", "git|5|eve")
+	commitment, err := bt.Commitment()
+	require.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		for index := 1; index < len(bt.ParticipantIDs); index++ {
+			proof, err := bt.InclusionProof(index)
+			require.NoError(t, err)
+			require.Equal(t, commitment, proof.Commitment)
+			require.Equal(t, bt.ParticipantIDs[index], proof.Participant.ParticipantID)
+			require.NoError(t, proof.Verify())
+
+			encoded, err := EncodeInclusionProof(proof, true)
+			require.NoError(t, err)
+			decoded, err := DecodeInclusionProof(encoded)
+			require.NoError(t, err)
+			require.Equal(t, proof, decoded)
+		}
+
+		// The initial state isn't a contribution.
+		_, err := bt.InclusionProof(0)
+		require.Error(t, err)
+	})
+	t.Run("commitment", func(t *testing.T) {
+		t.Parallel()
+		// The commitment doesn't depend on the JSON formatting.
+		var pretty bytes.Buffer
+		require.NoError(t, Encode(&pretty, bt, true))
+		decoded, err := Decode(&pretty)
+		require.NoError(t, err)
+		decodedCommitment, err := decoded.Commitment()
+		require.NoError(t, err)
+		require.Equal(t, commitment, decodedCommitment)
+
+		// But it depends on the content.
+		decoded.ParticipantIDs[2] = "git|2|bob"
+		decodedCommitment, err = decoded.Commitment()
+		require.NoError(t, err)
+		require.NotEqual(t, commitment, decodedCommitment)
+	})
+	t.Run("tampered", func(t *testing.T) {
+		t.Parallel()
+		tests := []struct {
+			name   string
+			tamper func(p *InclusionProof)
+		}{
+			{name: "commitment", tamper: func(p *InclusionProof) { p.Commitment[0] ^= 1 }},
+			{name: "powers hash", tamper: func(p *InclusionProof) { p.SubCeremonies[1].PowersHash[0] ^= 1 }},
+			{name: "participant id", tamper: func(p *InclusionProof) { p.Participant.ParticipantID = "git|2|bob" }},
+			{name: "running product", tamper: func(p *InclusionProof) { p.Participant.RunningProducts[1] = p.Previous.RunningProducts[1] }},
+			{name: "previous running product", tamper: func(p *InclusionProof) { p.Previous.RunningProducts[0] = p.Participant.RunningProducts[0] }},
+			{name: "index", tamper: func(p *InclusionProof) { p.Index++ }},
+			{name: "number of participants", tamper: func(p *InclusionProof) { p.NumParticipants++ }},
+			{name: "swapped paths", tamper: func(p *InclusionProof) { p.Path, p.PreviousPath = p.PreviousPath, p.Path }},
+			{name: "short path", tamper: func(p *InclusionProof) { p.Path = p.Path[:len(p.Path)-1] }},
+			{name: "missing sub-ceremony", tamper: func(p *InclusionProof) { p.Participant.PotPubKeys = p.Participant.PotPubKeys[:1] }},
+		}
+		for _, test := range tests {
+			test := test
+			t.Run(test.name, func(t *testing.T) {
+				t.Parallel()
+				proof, err := bt.InclusionProof(3)
+				require.NoError(t, err)
+				test.tamper(proof)
+				require.ErrorIs(t, proof.Verify(), ErrInvalidInclusionProof)
+			})
+		}
+
+		// A proof with a valid Merkle path but an invalid tau update.
+		tampered := newTestBatchTranscriptWithPowers(t, [][2]int{{4, 3}, {8, 3}}, "git|1|alice", "git|2|bob")
+		tampered.Transcripts[0].Witness.RunningProducts[1] = tampered.Transcripts[0].Witness.RunningProducts[2]
+		proof, err := tampered.InclusionProof(1)
+		require.NoError(t, err)
+		require.EqualError(t, proof.Verify(), "invalid inclusion proof: the running product of the 0-th sub-ceremony isn't an update with the participant potPubKey")
+	})
+	t.Run("merkle paths", func(t *testing.T) {
+		t.Parallel()
+		for size := 1; size <= 17; size++ {
+			leaves := make([][32]byte, size)
+			for i := range leaves {
+				leaves[i][0] = byte(i)
+			}
+			root := merkleRoot(leaves)
+			for m := range leaves {
+				pathRoot, ok := merklePathRoot(m, size, leaves[m], merklePath(m, leaves))
+				require.True(t, ok)
+				require.Equal(t, root, pathRoot, "size %d, leaf %d", size, m)
+			}
+		}
+	})
+	t.Run("invalid point", func(t *testing.T) {
+		t.Parallel()
+		proof, err := bt.InclusionProof(1)
+		require.NoError(t, err)
+		encoded, err := EncodeInclusionProof(proof, false)
+		require.NoError(t, err)
+		runningProduct := bt.Transcripts[1].Witness.RunningProducts[1].Bytes()
+		invalid := strings.Replace(string(encoded), hex.EncodeToString(runningProduct[:]), strings.Repeat("a", 2*len(runningProduct)), 1)
+		_, err = DecodeInclusionProof([]byte(invalid))
+		require.ErrorIs(t, err, contribution.ErrInvalidPoint)
+		require.ErrorContains(t, err, "participant.runningProducts[1]: ")
+	})
+}
+
+func TestParameterCheck(t *testing.T) {
+	t.Parallel()
+
+	params := []contribution.SubCeremonyParameters{{NumG1Powers: 4, NumG2Powers: 3}, {NumG1Powers: 8, NumG2Powers: 3}}
+	tests := []struct {
+		name    string
+		tamper  func(bt *BatchTranscript)
+		message string
+	}{
+		{
+			name:   "valid",
+			tamper: func(bt *BatchTranscript) {},
+		},
+		{
+			name:    "missing sub-ceremony",
+			tamper:  func(bt *BatchTranscript) { bt.Transcripts = bt.Transcripts[:1] },
+			message: "transcripts: parameter mismatch: there're 1 sub-ceremonies but 2 were expected",
+		},
+		{
+			name:    "declared powers",
+			tamper:  func(bt *BatchTranscript) { bt.Transcripts[1].NumG2Powers = 4 },
+			message: "transcripts[1].numG2Powers: parameter mismatch: got 4 but 3 were expected",
+		},
+		{
+			name: "actual powers",
+			tamper: func(bt *BatchTranscript) {
+				bt.Transcripts[0].PowersOfTau.G1Affines = append(bt.Transcripts[0].PowersOfTau.G1Affines, bt.Transcripts[0].PowersOfTau.G1Affines[0])
+			},
+			message: "transcripts[0].powersOfTau.G1Powers: parameter mismatch: there're 5 powers but numG1Powers is 4",
+		},
+		{
+			name: "witness length",
+			tamper: func(bt *BatchTranscript) {
+				bt.Transcripts[1].Witness.BLSSignatures = bt.Transcripts[1].Witness.BLSSignatures[:1]
+			},
+			message: "transcripts[1].witness.blsSignatures: parameter mismatch: there're 1 elements but 2 participant ids",
+		},
+		{
+			name:    "ecdsa signatures length",
+			tamper:  func(bt *BatchTranscript) { bt.ParticipantECDSASignatures = append(bt.ParticipantECDSASignatures, "") },
+			message: "participantEcdsaSignatures: parameter mismatch: there're 3 signatures but 2 participant ids",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			bt := newTestBatchTranscriptWithPowers(t, [][2]int{{4, 3}, {8, 3}}, "git|1|alice")
+			test.tamper(bt)
+			err := bt.ParameterCheck(params)
+			if test.message == "" {
+				require.NoError(t, err)
+				require.NoError(t, bt.Verify())
+				return
+			}
+			require.ErrorIs(t, err, contribution.ErrParameterMismatch)
+			require.EqualError(t, err, test.message)
+		})
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	bt := newTestBatchTranscriptWithPowers(f, [][2]int{{2, 2}}, "git|1|alice")
+	var buf bytes.Buffer
+	require.NoError(f, Encode(&buf, bt, false))
+	f.Add(buf.Bytes())
+	f.Add([]byte(`{"transcripts":[{"powersOfTau":{"G1Powers":["0x"]}}]}`))
+	f.Add([]byte(`{"participantIds":[""],"transcripts":[{"witness":{"blsSignatures":[""]}}]}`))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		bt, err := Decode(bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+		// Anything that decodes must survive a round trip.
+		var reencoded bytes.Buffer
+		require.NoError(t, Encode(&reencoded, bt, false))
+		decoded, err := Decode(&reencoded)
+		require.NoError(t, err)
+		require.Equal(t, bt, decoded)
+	})
+}
+
+func BenchmarkDecode(b *testing.B) {
+	bt := newTestBatchTranscriptWithPowers(b, [][2]int{{4096, 65}, {8192, 65}}, "", "")
+	var buf bytes.Buffer
+	require.NoError(b, Encode(&buf, bt, false))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := Decode(bytes.NewReader(buf.Bytes()))
+		require.NoError(b, err)
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	bt := newTestBatchTranscriptWithPowers(b, [][2]int{{4096, 65}, {8192, 65}}, make([]string, 16)...)
+
+	b.Run("batched", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = bt.Verify()
+		}
+	})
+	b.Run("exhaustive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = bt.VerifyExhaustive()
+		}
+	})
+}
+
+// newTestBatchTranscript returns a batch transcript with small sub-ceremonies which received one contribution
+// per provided identity. An empty identity means that the contribution isn't BLS signed.
+func newTestBatchTranscript(t testing.TB, identities ...string) *BatchTranscript {
+	return newTestBatchTranscriptWithPowers(t, [][2]int{{16, 5}, {32, 5}, {64, 5}, {128, 5}}, identities...)
+}
+
+func newTestBatchTranscriptWithPowers(t testing.TB, numPowers [][2]int, identities ...string) *BatchTranscript {
+	params := make([]contribution.SubCeremonyParameters, len(numPowers))
+	for i, np := range numPowers {
+		params[i] = contribution.SubCeremonyParameters{NumG1Powers: np[0], NumG2Powers: np[1]}
+	}
+	bt := NewBatchTranscript(params)
+
+	for _, identity := range identities {
+		bc := bt.CurrentBatchContribution()
+		require.NoError(t, bc.Contribute(identity))
+
+		signatures := Signatures{BLS: make([]*bls12381.G1Affine, len(bc.Contributions))}
+		for i := range bc.Contributions {
+			signatures.BLS[i] = bc.Contributions[i].BLSSignature
+		}
+		require.NoError(t, bt.Apply(identity, bc, signatures))
+	}
+
+	return bt
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
+}
+
+func g1PointJSON(p bls12381.G1Affine) string {
+	pBytes := p.Bytes()
+	return `"0x` + hex.EncodeToString(pBytes[:]) + `"`
+}
@@ -0,0 +1,50 @@
+package transcript
+
+import (
+	"fmt"
+
+	"github.com/jsign/go-kzg-ceremony-client/contribution"
+)
+
+// Parameters returns the declared parameters of each sub-ceremony.
+func (bt *BatchTranscript) Parameters() []contribution.SubCeremonyParameters {
+	params := make([]contribution.SubCeremonyParameters, len(bt.Transcripts))
+	for i, transcript := range bt.Transcripts {
+		params[i] = contribution.SubCeremonyParameters{NumG1Powers: transcript.NumG1Powers, NumG2Powers: transcript.NumG2Powers}
+	}
+	return params
+}
+
+// ParameterCheck implements the spec `parameter_check`, checking that the number of sub-ceremonies and their
+// number of powers match the ceremony parameters. It also checks that every witness and the ECDSA signatures
+// have one entry per participant.
+func (bt *BatchTranscript) ParameterCheck(params []contribution.SubCeremonyParameters) error {
+	if len(bt.Transcripts) != len(params) {
+		return &contribution.ValidationError{Path: "transcripts", Err: fmt.Errorf("%w: there're %d sub-ceremonies but %d were expected", contribution.ErrParameterMismatch, len(bt.Transcripts), len(params))}
+	}
+	numParticipants := len(bt.ParticipantIDs)
+	if len(bt.ParticipantECDSASignatures) != numParticipants {
+		return &contribution.ValidationError{Path: "participantEcdsaSignatures", Err: fmt.Errorf("%w: there're %d signatures but %d participant ids", contribution.ErrParameterMismatch, len(bt.ParticipantECDSASignatures), numParticipants)}
+	}
+	for i := range bt.Transcripts {
+		transcript := &bt.Transcripts[i]
+		path := contribution.IndexPath("transcripts", i)
+		if err := contribution.CheckPowersParameters(path, params[i], transcript.NumG1Powers, transcript.NumG2Powers, len(transcript.PowersOfTau.G1Affines), len(transcript.PowersOfTau.G2Affines)); err != nil {
+			return err
+		}
+		witnessLengths := []struct {
+			field  string
+			length int
+		}{
+			{"runningProducts", len(transcript.Witness.RunningProducts)},
+			{"potPubkeys", len(transcript.Witness.PotPubKeys)},
+			{"blsSignatures", len(transcript.Witness.BLSSignatures)},
+		}
+		for _, wl := range witnessLengths {
+			if wl.length != numParticipants {
+				return &contribution.ValidationError{Path: contribution.JoinPath(path, "witness."+wl.field), Err: fmt.Errorf("%w: there're %d elements but %d participant ids", contribution.ErrParameterMismatch, wl.length, numParticipants)}
+			}
+		}
+	}
+	return nil
+}